@@ -0,0 +1,380 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+// cheatSheetLanguage is the language of the commands kept by pet, navi and tldr
+const cheatSheetLanguage = "bash"
+
+// petSnippet is one [[snippets]] table of a pet snippet.toml file
+type petSnippet struct {
+	Description string
+	Command     string
+	Tags        []string
+}
+
+// readPetSnippets reads a pet snippet.toml file, the description of each command is also its name
+func readPetSnippets(entries *importEntries, data []byte, name string) error {
+	snippets, err := parsePetSnippets(string(data))
+	if err != nil {
+		return err
+	}
+	for _, pet := range snippets {
+		snippet := models.CodeSnippet{
+			Name:        pet.Description,
+			Code:        pet.Command,
+			Language:    cheatSheetLanguage,
+			Tags:        pet.Tags,
+			Description: pet.Description,
+		}
+		entries.add(snippet, name, cheatSheetKey(pet.Description, pet.Command))
+	}
+	return nil
+}
+
+// cheatSheetKey identifies an entry within its file by its description, or by its command when it has none
+func cheatSheetKey(description string, command string) string {
+	if description != "" {
+		return description
+	}
+	return command
+}
+
+// parsePetSnippets parses the toml pet writes: [[snippets]] tables of string and string list keys.
+// Other tables and keys are read past and ignored.
+func parsePetSnippets(data string) ([]petSnippet, error) {
+	s := tomlScanner{data: data}
+	var snippets []petSnippet
+	var current *petSnippet
+
+	for {
+		s.skipSpace(true)
+		if s.done() {
+			return snippets, nil
+		}
+
+		if s.peek() == '[' {
+			end := strings.IndexByte(s.data[s.i:], '\n')
+			if end < 0 {
+				end = len(s.data) - s.i
+			}
+			header := strings.TrimSpace(stripTOMLComment(s.data[s.i : s.i+end]))
+			s.i += end
+			current = nil
+			if header == "[[snippets]]" {
+				snippets = append(snippets, petSnippet{})
+				current = &snippets[len(snippets)-1]
+			}
+			continue
+		}
+
+		key, err := s.readKey()
+		if err != nil {
+			return nil, s.errorf("%v", err)
+		}
+		s.skipSpace(false)
+		if s.done() || s.peek() != '=' {
+			return nil, s.errorf("expected = after %q", key)
+		}
+		s.i++
+		s.skipSpace(false)
+		value, err := s.readValue()
+		if err != nil {
+			return nil, s.errorf("%s: %v", key, err)
+		}
+
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "description":
+			current.Description, _ = value.(string)
+		case "command":
+			current.Command, _ = value.(string)
+		case "tag":
+			switch v := value.(type) {
+			case []string:
+				current.Tags = v
+			case string:
+				current.Tags = strings.Fields(v)
+			}
+		}
+	}
+}
+
+// tomlScanner reads the parts of toml used by pet snippet files
+type tomlScanner struct {
+	data string
+	i    int
+}
+
+func (s *tomlScanner) done() bool {
+	return s.i >= len(s.data)
+}
+
+func (s *tomlScanner) peek() byte {
+	return s.data[s.i]
+}
+
+func (s *tomlScanner) errorf(format string, args ...any) error {
+	line := strings.Count(s.data[:min(s.i, len(s.data))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments, and line endings when newlines is set
+func (s *tomlScanner) skipSpace(newlines bool) {
+	for !s.done() {
+		switch c := s.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			s.i++
+		case c == '\n' && newlines:
+			s.i++
+		case c == '#':
+			for !s.done() && s.peek() != '\n' {
+				s.i++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *tomlScanner) readKey() (string, error) {
+	if c := s.peek(); c == '"' || c == '\'' {
+		return s.readString()
+	}
+	start := s.i
+	for !s.done() && strings.IndexByte(" \t=\r\n", s.peek()) < 0 {
+		s.i++
+	}
+	if start == s.i {
+		return "", fmt.Errorf("expected a key")
+	}
+	return s.data[start:s.i], nil
+}
+
+// readValue reads a string or a list of strings, any other value is returned as its text
+func (s *tomlScanner) readValue() (any, error) {
+	if s.done() {
+		return nil, fmt.Errorf("expected a value")
+	}
+	switch s.peek() {
+	case '"', '\'':
+		return s.readString()
+	case '[':
+		s.i++
+		var list []string
+		for {
+			s.skipSpace(true)
+			if s.done() {
+				return nil, fmt.Errorf("unterminated list")
+			}
+			if s.peek() == ']' {
+				s.i++
+				return list, nil
+			}
+			value, err := s.readValue()
+			if err != nil {
+				return nil, err
+			}
+			if str, ok := value.(string); ok {
+				list = append(list, str)
+			}
+			s.skipSpace(true)
+			if !s.done() && s.peek() == ',' {
+				s.i++
+			}
+		}
+	}
+
+	start := s.i
+	for !s.done() && strings.IndexByte(",]#\r\n", s.peek()) < 0 {
+		s.i++
+	}
+	return strings.TrimSpace(s.data[start:s.i]), nil
+}
+
+// readString reads a basic or literal string, either of which may be multi-line
+func (s *tomlScanner) readString() (string, error) {
+	quote := s.data[s.i : s.i+1]
+	if strings.HasPrefix(s.data[s.i:], quote+quote+quote) {
+		s.i += 3
+		// a line ending straight after the opening quotes is not part of the string
+		if strings.HasPrefix(s.data[s.i:], "\r\n") {
+			s.i += 2
+		} else if strings.HasPrefix(s.data[s.i:], "\n") {
+			s.i++
+		}
+		return s.readStringUntil(quote+quote+quote, quote == `"`)
+	}
+	s.i++
+	return s.readStringUntil(quote, quote == `"`)
+}
+
+func (s *tomlScanner) readStringUntil(end string, escapes bool) (string, error) {
+	var b strings.Builder
+	multiline := len(end) == 3
+	for !s.done() {
+		if strings.HasPrefix(s.data[s.i:], end) {
+			// quotes directly before the closing ones of a multi-line string are part of it
+			if multiline && strings.HasPrefix(s.data[s.i+1:], end) {
+				b.WriteByte(s.peek())
+				s.i++
+				continue
+			}
+			s.i += len(end)
+			return b.String(), nil
+		}
+
+		c := s.peek()
+		if c == '\n' && !multiline {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if c != '\\' || !escapes {
+			b.WriteByte(c)
+			s.i++
+			continue
+		}
+
+		s.i++
+		if s.done() {
+			break
+		}
+		switch e := s.peek(); e {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(e)
+		case 'u', 'U':
+			size := 4
+			if e == 'U' {
+				size = 8
+			}
+			if s.i+size >= len(s.data) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(s.data[s.i+1:s.i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(code))
+			s.i += size
+		default:
+			// a backslash at the end of a line in a multi-line string joins it to the next non blank text
+			if multiline && (e == ' ' || e == '\t' || e == '\r' || e == '\n') {
+				for !s.done() && strings.IndexByte(" \t\r\n", s.peek()) >= 0 {
+					s.i++
+				}
+				continue
+			}
+			return "", fmt.Errorf("invalid escape \\%c", e)
+		}
+		s.i++
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// stripTOMLComment removes a comment from a table header line
+func stripTOMLComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// readNaviCheats reads a navi .cheat file. A % line sets the tags of the commands after it, a # line describes
+// the command below it, which runs until a blank line or the next marker. Variable ($), comment (;) and
+// extension (@) lines are not part of any command.
+func readNaviCheats(entries *importEntries, data []byte, name string) error {
+	var tags []string
+	var description string
+	var command []string
+
+	flush := func() {
+		if len(command) == 0 {
+			return
+		}
+		code := strings.Join(command, "\n")
+		snippet := models.CodeSnippet{
+			Name:        description,
+			Code:        code,
+			Language:    cheatSheetLanguage,
+			Tags:        tags,
+			Description: description,
+		}
+		entries.add(snippet, name, cheatSheetKey(description, code))
+		description = ""
+		command = nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "%"):
+			flush()
+			tags = nil
+			for _, tag := range strings.Split(trimmed[1:], ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			description = strings.TrimSpace(trimmed[1:])
+		case strings.HasPrefix(trimmed, "$"), strings.HasPrefix(trimmed, "@"):
+			flush()
+		case strings.HasPrefix(trimmed, ";"), strings.HasPrefix(trimmed, "```"):
+		default:
+			command = append(command, line)
+		}
+	}
+	flush()
+	return nil
+}
+
+// readTldrPages reads a tldr page. Every example is named after the page and tagged with it, the text
+// before the example is its description.
+func readTldrPages(entries *importEntries, data []byte, name string) error {
+	title := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	var description string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "# "):
+			title = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "- "):
+			description = strings.TrimSuffix(strings.TrimSpace(line[2:]), ":")
+		case len(line) > 1 && line[0] == '`' && line[len(line)-1] == '`':
+			code := line[1 : len(line)-1]
+			snippet := models.CodeSnippet{
+				Name:        title,
+				Code:        code,
+				Language:    cheatSheetLanguage,
+				Tags:        []string{title},
+				Description: description,
+			}
+			entries.add(snippet, name, cheatSheetKey(description, code))
+			description = ""
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+func TestParsePetSnippets(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want []petSnippet
+	}{
+		{
+			name: "basic strings and tag list",
+			toml: `[[snippets]]
+  description = "list files"
+  command = "ls -la"
+  tag = ["fs", "list"]
+  output = ""
+
+[[snippets]]
+  description = 'literal'
+  command = 'C:\temp\n'
+  tag = "a b"
+`,
+			want: []petSnippet{
+				{Description: "list files", Command: "ls -la", Tags: []string{"fs", "list"}},
+				{Description: "literal", Command: `C:\temp\n`, Tags: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "escapes",
+			toml: `[[snippets]]
+command = "tab\tquote\" backslash\\ \u00e9 \U0001F600"
+`,
+			want: []petSnippet{{Command: "tab\tquote\" backslash\\ é 😀"}},
+		},
+		{
+			name: "multi-line strings",
+			toml: "[[snippets]]\r\n" +
+				"command = \"\"\"\r\nfor f in *; do\r\n  echo \"$f\"\r\ndone\"\"\"\r\n" +
+				"description = '''\nno \\escapes\n'''\n",
+			want: []petSnippet{{Command: "for f in *; do\r\n  echo \"$f\"\r\ndone", Description: "no \\escapes\n"}},
+		},
+		{
+			name: "line ending backslash",
+			toml: `[[snippets]]
+command = """
+docker run \
+    --rm \
+    alpine"""
+`,
+			want: []petSnippet{{Command: "docker run --rm alpine"}},
+		},
+		{
+			name: "quotes before the closing quotes",
+			toml: `[[snippets]]
+command = """say ""hi"""""
+`,
+			want: []petSnippet{{Command: `say ""hi""`}},
+		},
+		{
+			name: "comments and other tables",
+			toml: `# pet snippets
+[settings]
+command = "ignored"
+
+[[snippets]] # the only one
+  command = "echo # not a comment" # a comment
+  "description" = "quoted key"
+  count = 3
+`,
+			want: []petSnippet{{Command: "echo # not a comment", Description: "quoted key"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePetSnippets(tt.toml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePetSnippets() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePetSnippetsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{"unterminated string", "[[snippets]]\ncommand = \"ls\n", "line 2: command: unterminated string"},
+		{"unterminated multi-line string", "[[snippets]]\ncommand = \"\"\"ls\n", "command: unterminated string"},
+		{"invalid escape", "[[snippets]]\ncommand = \"\\q\"\n", `command: invalid escape \q`},
+		{"short unicode escape", "[[snippets]]\ncommand = \"\\u12\"\n", "command: invalid unicode escape"},
+		{"missing equals", "[[snippets]]\ncommand \"ls\"\n", `expected = after "command"`},
+		{"unterminated list", "[[snippets]]\ntag = [\"a\",\n", "tag: unterminated list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePetSnippets(tt.toml)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parsePetSnippets() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadNaviCheats(t *testing.T) {
+	cheat := `% git, vcs
+
+# Show the status
+git status
+
+# Commit with a message
+git commit -m <message>
+$ message: echo "wip"
+; comments are not part of a command
+# Stage and commit
+git add .
+git commit
+@ other
+
+% docker
+docker ps
+` + "```" + `
+# Remove a container
+docker rm <id>
+`
+	want := []models.CodeSnippet{
+		{Name: "Show the status", Code: "git status", Tags: []string{"git", "vcs"}, Description: "Show the status"},
+		{Name: "Commit with a message", Code: "git commit -m <message>", Tags: []string{"git", "vcs"}, Description: "Commit with a message"},
+		{Name: "Stage and commit", Code: "git add .\ngit commit", Tags: []string{"git", "vcs"}, Description: "Stage and commit"},
+		{Code: "docker ps", Tags: []string{"docker"}},
+		{Name: "Remove a container", Code: "docker rm <id>", Tags: []string{"docker"}, Description: "Remove a container"},
+	}
+
+	entries := importEntries{format: InputNavi}
+	if err := readNaviCheats(&entries, []byte(cheat), "git.cheat"); err != nil {
+		t.Fatal(err)
+	}
+	checkCheatSheet(t, entries.snippets, want)
+}
+
+func TestReadTldrPages(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		page string
+		want []models.CodeSnippet
+	}{
+		{
+			name: "page with title",
+			file: "common/tar.md",
+			page: "# tar\n\n> Archiving utility.\n\n- Create an archive from files:\n\n`tar cf {{target.tar}} {{file1}}`\n\n- Extract an archive:\n\n`tar xf {{source.tar}}`\n",
+			want: []models.CodeSnippet{
+				{Name: "tar", Code: "tar cf {{target.tar}} {{file1}}", Tags: []string{"tar"}, Description: "Create an archive from files"},
+				{Name: "tar", Code: "tar xf {{source.tar}}", Tags: []string{"tar"}, Description: "Extract an archive"},
+			},
+		},
+		{
+			name: "title from the file name",
+			file: "linux/ls.md",
+			page: "- List files:\n`ls`\n\n`ls -a`\n",
+			want: []models.CodeSnippet{
+				{Name: "ls", Code: "ls", Tags: []string{"ls"}, Description: "List files"},
+				{Name: "ls", Code: "ls -a", Tags: []string{"ls"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := importEntries{format: InputTldr}
+			if err := readTldrPages(&entries, []byte(tt.page), tt.file); err != nil {
+				t.Fatal(err)
+			}
+			checkCheatSheet(t, entries.snippets, tt.want)
+		})
+	}
+}
+
+// checkCheatSheet compares the snippets read from a cheat sheet, all of which are bash, ignoring their uuid and source
+func checkCheatSheet(t *testing.T, got []models.CodeSnippet, want []models.CodeSnippet) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d snippets, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		want[i].Language = cheatSheetLanguage
+		g := got[i]
+		g.Uuid, g.Source = want[i].Uuid, want[i].Source
+		if !reflect.DeepEqual(g, want[i]) {
+			t.Errorf("snippet %d read as %+v, want %+v", i, g, want[i])
+		}
+	}
+}
+
+func TestImportEntriesAdd(t *testing.T) {
+	read := func(file string, name string, snippets ...models.CodeSnippet) importEntries {
+		entries := importEntries{format: InputPet, file: file}
+		for _, snippet := range snippets {
+			entries.add(snippet, name, cheatSheetKey(snippet.Description, snippet.Code))
+		}
+		return entries
+	}
+	list := models.CodeSnippet{Description: "list", Code: "ls"}
+	listAll := models.CodeSnippet{Description: "list", Code: "ls -a"}
+	unnamed := models.CodeSnippet{Code: "pwd"}
+	empty := models.CodeSnippet{Description: "empty", Code: "  \n"}
+
+	first := read("/home/a/snippet.toml", "snippet.toml", list, listAll, unnamed, list, empty)
+	if want := (readSummary{Entries: 5, Skipped: 1, Duplicates: 1}); first.summary != want {
+		t.Errorf("summary is %+v, want %+v", first.summary, want)
+	}
+	if len(first.snippets) != 3 {
+		t.Fatalf("kept %d snippets, want 3", len(first.snippets))
+	}
+	seen := map[string]bool{}
+	for _, snippet := range first.snippets {
+		if seen[snippet.Uuid.String()] {
+			t.Errorf("uuid %s given to more than one snippet", snippet.Uuid)
+		}
+		seen[snippet.Uuid.String()] = true
+		if snippet.Source != "/home/a/snippet.toml" {
+			t.Errorf("source is %q, want the file read", snippet.Source)
+		}
+	}
+
+	// the uuids come from the file's name within the import, not where it was read from
+	again := read("/tmp/copy/snippet.toml", "snippet.toml", list, listAll, unnamed, list, empty)
+	for i := range first.snippets {
+		if again.snippets[i].Uuid != first.snippets[i].Uuid {
+			t.Errorf("snippet %d has uuid %s on the second import, want %s", i, again.snippets[i].Uuid, first.snippets[i].Uuid)
+		}
+	}
+
+	other := read("/home/a/other.toml", "other.toml", list)
+	if other.snippets[0].Uuid == first.snippets[0].Uuid {
+		t.Errorf("the same entry in another file has the same uuid")
+	}
+}
@@ -1,17 +1,19 @@
 package cli
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/Ryan-Har/csnip/common"
 	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/config"
 	"github.com/Ryan-Har/csnip/database"
 	"github.com/alecthomas/chroma/v2/formatters"
-	"github.com/alecthomas/chroma/v2/lexers"
-	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/atotto/clipboard"
 	"github.com/google/uuid"
 )
@@ -20,6 +22,10 @@ type CLIOpts struct {
 	OptType     OptType
 	FlagOptions map[FlagOption]string
 	Theme       string
+	Formatter   string
+	Clipboard   bool
+	PageSize    int64
+	Config      config.Config
 }
 
 type OptType string
@@ -29,6 +35,41 @@ const (
 	OptTypeUpdate OptType = "UPDATE"
 	OptTypeAdd    OptType = "ADD"
 	OptTypeDelete OptType = "DELETE"
+	OptTypeEdit   OptType = "EDIT"
+	OptTypeSearch OptType = "SEARCH"
+	OptTypeTags   OptType = "TAGS"
+
+	OptTypeHistory OptType = "HISTORY"
+	OptTypeRevert  OptType = "REVERT"
+	OptTypeDiff    OptType = "DIFF"
+
+	OptTypeRestore    OptType = "RESTORE"
+	OptTypeTrashList  OptType = "TRASH_LIST"
+	OptTypeTrashEmpty OptType = "TRASH_EMPTY"
+
+	OptTypeGroupCreate OptType = "GROUP_CREATE"
+	OptTypeGroupList   OptType = "GROUP_LIST"
+	OptTypeGroupShow   OptType = "GROUP_SHOW"
+	OptTypeGroupAdd    OptType = "GROUP_ADD"
+	OptTypeGroupRemove OptType = "GROUP_REMOVE"
+	OptTypeGroupDelete OptType = "GROUP_DELETE"
+
+	OptTypeConfigGet  OptType = "CONFIG_GET"
+	OptTypeConfigSet  OptType = "CONFIG_SET"
+	OptTypeConfigList OptType = "CONFIG_LIST"
+
+	OptTypeCopyTo        OptType = "COPY_TO"
+	OptTypeLibraryList   OptType = "LIBRARY_LIST"
+	OptTypeLibraryAdd    OptType = "LIBRARY_ADD"
+	OptTypeLibraryRemove OptType = "LIBRARY_REMOVE"
+
+	OptTypeExport OptType = "EXPORT"
+	OptTypeImport OptType = "IMPORT"
+
+	OptTypeExportMarkdown OptType = "EXPORT_MARKDOWN"
+
+	OptTypeShow OptType = "SHOW"
+	OptTypeSite OptType = "SITE"
 )
 
 func (o OptType) String() string {
@@ -43,33 +84,80 @@ const (
 	FlagOptionTag         FlagOption = "Tag"
 	FlagOptionAll         FlagOption = "All"
 	FlagOptionCode        FlagOption = "Code"
+	FlagOptionCodeFile    FlagOption = "CodeFile"
 	FlagOptionName        FlagOption = "Name"
 	FlagOptionDescription FlagOption = "Description"
+	FlagOptionTagMatch    FlagOption = "TagMatch"
+	FlagOptionGroup       FlagOption = "Group"
+	FlagOptionQuery       FlagOption = "Query"
+	FlagOptionLimit       FlagOption = "Limit"
+	FlagOptionConfigKey   FlagOption = "ConfigKey"
+	FlagOptionConfigValue FlagOption = "ConfigValue"
+	FlagOptionPurge       FlagOption = "Purge"
+	FlagOptionOlderThan   FlagOption = "OlderThan"
+	FlagOptionVersion     FlagOption = "Version"
+	FlagOptionFromVersion FlagOption = "FromVersion"
+	FlagOptionSource      FlagOption = "Source"
+	FlagOptionSince       FlagOption = "Since"
+	FlagOptionBefore      FlagOption = "Before"
+	FlagOptionPage        FlagOption = "Page"
+	FlagOptionSort        FlagOption = "Sort"
+	FlagOptionReverse     FlagOption = "Reverse"
+	FlagOptionOutput      FlagOption = "Output"
+	FlagOptionFormat      FlagOption = "Format"
+	FlagOptionLibrary     FlagOption = "Library"
+	FlagOptionPath        FlagOption = "Path"
+	FlagOptionHistory     FlagOption = "History"
+	FlagOptionConflict    FlagOption = "Conflict"
+	FlagOptionDryRun      FlagOption = "DryRun"
+	FlagOptionInput       FlagOption = "Input"
+	FlagOptionTitle       FlagOption = "Title"
+	FlagOptionRender      FlagOption = "Render"
 )
 
-func (c *CLIOpts) Run(db database.DatabaseInteractions) {
+// RequiresDatabase reports whether the operation needs an open database to run
+func (c *CLIOpts) RequiresDatabase() bool {
+	switch c.OptType {
+	case OptTypeConfigGet, OptTypeConfigSet, OptTypeConfigList,
+		OptTypeLibraryList, OptTypeLibraryAdd, OptTypeLibraryRemove:
+		return false
+	}
+	return true
+}
+
+func (c *CLIOpts) Run(ctx context.Context, db database.DatabaseInteractions) {
 	switch c.OptType {
 	case OptTypeGet:
-		snippets, err := c.handleGetOptType(db)
+		page, err := c.handleGetOptType(ctx, db)
 		if err != nil {
-			if errors.Is(err, database.ErrNoSnippetsFound) {
-
-			}
 			fmt.Println("Error occured retrieving code snippets: ", err)
 			os.Exit(1)
 		}
-		if len(snippets) < 1 {
+		if c.structuredOutput() {
+			err := writeItems(c, os.Stdout, page.Snippets, c.FlagOptions[FlagOptionUUID] != "", snippetColumns, snippetRow)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if len(page.Snippets) < 1 {
 			fmt.Println("No code snippets found with the provided filters")
+			os.Exit(0)
 		}
 		// only display when searching with uuid
-		if len(snippets) == 1 && c.FlagOptions[FlagOptionUUID] != "" {
-			displaySingleSnippet(snippets[0], c.Theme)
+		if len(page.Snippets) == 1 && c.FlagOptions[FlagOptionUUID] != "" {
+			c.displaySingleSnippet(page.Snippets[0])
+			if err := db.RecordSnippetUse(ctx, page.Snippets[0].Uuid); err != nil {
+				fmt.Println(err)
+			}
 		} else {
-			displaySnippetList(snippets)
+			displaySnippetList(page.Snippets)
+			fmt.Printf("Showing %d-%d of %d\n", page.Offset+1, page.Offset+int64(len(page.Snippets)), page.Total)
 		}
 		os.Exit(0)
 	case OptTypeAdd:
-		err := c.handleAddOptType(db)
+		err := c.handleAddOptType(ctx, db)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -77,20 +165,139 @@ func (c *CLIOpts) Run(db database.DatabaseInteractions) {
 		fmt.Println("Code snippet added to database")
 		os.Exit(0)
 	case OptTypeUpdate:
-		err := c.handleUpdateOptType(db)
+		err := c.handleUpdateOptType(ctx, db)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 		fmt.Println("Code snippet updated")
 		os.Exit(0)
+	case OptTypeEdit:
+		err := c.handleEditOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	case OptTypeDelete:
-		err := c.handleDeleteOptType(db)
+		err := c.handleDeleteOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if c.FlagOptions[FlagOptionPurge] != "" {
+			fmt.Println("Code snippet permanently deleted")
+		} else {
+			fmt.Println("Code snippet moved to trash, use restore to bring it back")
+		}
+		os.Exit(0)
+	case OptTypeHistory, OptTypeRevert, OptTypeDiff:
+		err := c.handleHistoryOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeRestore, OptTypeTrashList, OptTypeTrashEmpty:
+		err := c.handleTrashOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeGroupCreate, OptTypeGroupList, OptTypeGroupShow, OptTypeGroupAdd, OptTypeGroupRemove, OptTypeGroupDelete:
+		err := c.handleGroupOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeTags:
+		tags, err := db.ListTags(ctx)
+		if err != nil {
+			fmt.Println("Error occured retrieving tags: ", err)
+			os.Exit(1)
+		}
+		if len(tags) < 1 {
+			fmt.Println("No tags found")
+			os.Exit(0)
+		}
+		displayTagList(tags)
+		os.Exit(0)
+	case OptTypeSearch:
+		results, err := c.handleSearchOptType(ctx, db)
+		if err != nil {
+			fmt.Println("Error occured searching code snippets: ", err)
+			os.Exit(1)
+		}
+		if c.structuredOutput() {
+			if err := writeItems(c, os.Stdout, results, false, searchResultColumns, searchResultRow); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if len(results) < 1 {
+			fmt.Println("No code snippets matched the search")
+			os.Exit(0)
+		}
+		displaySearchResults(results)
+		os.Exit(0)
+	case OptTypeConfigGet, OptTypeConfigSet, OptTypeConfigList:
+		err := c.handleConfigOptType()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeCopyTo:
+		err := c.handleCopyToOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeExport:
+		err := c.handleExportOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeImport:
+		err := c.handleImportOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeExportMarkdown:
+		err := c.handleExportMarkdownOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeShow:
+		err := c.handleShowOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeSite:
+		err := c.handleSiteOptType(ctx, db)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case OptTypeLibraryList, OptTypeLibraryAdd, OptTypeLibraryRemove:
+		err := c.handleLibraryOptType()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		fmt.Println("Code snippet deleted")
 		os.Exit(0)
 	default:
 		fmt.Println("Unknown operation")
@@ -99,29 +306,29 @@ func (c *CLIOpts) Run(db database.DatabaseInteractions) {
 
 }
 
-func (c *CLIOpts) handleAddOptType(db database.DatabaseInteractions) error {
+func (c *CLIOpts) handleAddOptType(ctx context.Context, db database.DatabaseInteractions) error {
 	fOpts := c.FlagOptions
 	var cs models.CodeSnippet
 
 	cs.Code = fOpts[FlagOptionCode]
 
-	if common.ValidateLanguage(fOpts[FlagOptionLanguage]) {
-		cs.Language = fOpts[FlagOptionLanguage]
-	} else {
-		return fmt.Errorf("invalid language supplied: %v", fOpts[FlagOptionLanguage])
+	lang, err := resolveLanguage(fOpts[FlagOptionLanguage], fOpts[FlagOptionCodeFile], cs.Code)
+	if err != nil {
+		return err
 	}
+	cs.Language = lang
 
 	if name, ok := fOpts[FlagOptionName]; ok {
 		cs.Name = name
 	}
 	if tags, ok := fOpts[FlagOptionTag]; ok {
-		cs.Tags = tags
+		cs.Tags = common.ParseTags(tags)
 	}
 	if description, ok := fOpts[FlagOptionDescription]; ok {
 		cs.Description = description
 	}
 
-	err := db.AddNewSnippet(cs)
+	_, err = db.AddNewSnippet(ctx, cs)
 	if err != nil {
 		return fmt.Errorf("unable to handle ADD with the provided options %w", err)
 	}
@@ -129,62 +336,127 @@ func (c *CLIOpts) handleAddOptType(db database.DatabaseInteractions) error {
 	return nil
 }
 
-func (c *CLIOpts) handleGetOptType(db database.DatabaseInteractions) ([]models.CodeSnippet, error) {
+func (c *CLIOpts) handleGetOptType(ctx context.Context, db database.DatabaseInteractions) (database.SnippetPage, error) {
 	fOpts := c.FlagOptions
-	var snippets []models.CodeSnippet
+	var page database.SnippetPage
+
+	if fOpts[FlagOptionUUID] != "" {
+		id, err := uuid.Parse(fOpts[FlagOptionUUID])
+		if err != nil {
+			return page, fmt.Errorf("unable to parse provided UUID")
+		}
+		idSnip, err := db.GetSnippetByUUID(ctx, id)
+		if err != nil {
+			return page, err
+		}
+		page.Snippets = append(page.Snippets, idSnip)
+		page.Total = 1
+		return page, nil
+	}
+
+	filter, err := c.snippetFilter()
+	if err != nil {
+		return page, err
+	}
+	filter.Sort, err = database.ParseSortOrder(fOpts[FlagOptionSort])
+	if err != nil {
+		return page, err
+	}
+	filter.Reverse = fOpts[FlagOptionReverse] != ""
 
-	if len(fOpts) == 2 && fOpts[FlagOptionLanguage] != "" && fOpts[FlagOptionTag] != "" {
-		return db.GetSnippetsByLanguageAndTag(fOpts[FlagOptionLanguage], fOpts[FlagOptionTag])
+	filter.Limit = c.PageSize
+	if limit, err := strconv.ParseInt(fOpts[FlagOptionLimit], 10, 64); err == nil && limit > 0 {
+		filter.Limit = limit
 	}
-	if fOpts[FlagOptionAll] != "" {
-		return db.GetSnippets(1, 100)
+	if pageNumber, err := strconv.ParseInt(fOpts[FlagOptionPage], 10, 64); err == nil && pageNumber > 1 {
+		filter.Offset = (pageNumber - 1) * filter.Limit
 	}
-	if fOpts[FlagOptionLanguage] != "" {
-		return db.GetSnippetsByLanguage(fOpts[FlagOptionLanguage])
+
+	return db.QuerySnippetsPage(ctx, filter)
+}
+
+// snippetFilter builds a query filter from the filter flags that were set
+func (c *CLIOpts) snippetFilter() (database.SnippetFilter, error) {
+	fOpts := c.FlagOptions
+	var filter database.SnippetFilter
+
+	if langs := fOpts[FlagOptionLanguage]; langs != "" {
+		for _, lang := range strings.Split(langs, ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				filter.Languages = append(filter.Languages, lang)
+			}
+		}
 	}
 	if fOpts[FlagOptionTag] != "" {
-		return db.GetSnippetsByTag(fOpts[FlagOptionTag])
+		filter.Tags = common.ParseTags(fOpts[FlagOptionTag])
+		if fOpts[FlagOptionTagMatch] == "all" {
+			filter.TagMatch = database.TagMatchAll
+		}
 	}
-	if fOpts[FlagOptionUUID] != "" {
-		id, err := uuid.Parse(fOpts[FlagOptionUUID])
+	filter.Group = fOpts[FlagOptionGroup]
+	filter.Source = fOpts[FlagOptionSource]
+	filter.Name = fOpts[FlagOptionName]
+
+	if since := fOpts[FlagOptionSince]; since != "" {
+		t, err := common.ParseDateOrAge(since)
 		if err != nil {
-			return snippets, fmt.Errorf("unable to parse provided UUID")
+			return filter, err
 		}
-		idSnip, err := db.GetSnippetByUUID(id)
+		filter.ModifiedAfter = t
+	}
+	if before := fOpts[FlagOptionBefore]; before != "" {
+		t, err := common.ParseDateOrAge(before)
 		if err != nil {
-			return snippets, err
+			return filter, err
 		}
-		snippets = append(snippets, idSnip)
-		return snippets, nil
+		filter.ModifiedBefore = t
 	}
-	return snippets, fmt.Errorf("unable to handle GET with the provided options")
+	return filter, nil
 }
 
-func (c *CLIOpts) handleUpdateOptType(db database.DatabaseInteractions) error {
+func (c *CLIOpts) handleUpdateOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
 	var cs models.CodeSnippet
-	cs.Code = c.FlagOptions[FlagOptionCode]
+	cs.Code = fOpts[FlagOptionCode]
+	cs.Name = fOpts[FlagOptionName]
+	cs.Description = fOpts[FlagOptionDescription]
+	cs.Source = fOpts[FlagOptionSource]
+	if tags, ok := fOpts[FlagOptionTag]; ok {
+		cs.Tags = common.ParseTags(tags)
+	}
+	if lang, ok := fOpts[FlagOptionLanguage]; ok {
+		canonical, err := canonicalLanguage(lang)
+		if err != nil {
+			return err
+		}
+		cs.Language = canonical
+	}
 
-	id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+	id, err := uuid.Parse(fOpts[FlagOptionUUID])
 	if err != nil {
 		return fmt.Errorf("unable to parse provided UUID")
 	}
 
-	_, err = db.UpdateSnippet(id, cs)
+	_, err = db.UpdateSnippet(ctx, id, cs)
 	if err != nil {
-		return fmt.Errorf("unable to handle ADD with the provided options %w", err)
+		return fmt.Errorf("unable to handle UPDATE with the provided options %w", err)
 	}
 
 	return nil
 }
 
-func (c *CLIOpts) handleDeleteOptType(db database.DatabaseInteractions) error {
+func (c *CLIOpts) handleDeleteOptType(ctx context.Context, db database.DatabaseInteractions) error {
 
 	id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
 	if err != nil {
 		return fmt.Errorf("unable to parse provided UUID")
 	}
 
-	err = db.DeleteSnippetByUUID(id)
+	if c.FlagOptions[FlagOptionPurge] != "" {
+		err = db.PurgeSnippetByUUID(ctx, id)
+	} else {
+		err = db.DeleteSnippetByUUID(ctx, id)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to handle DELETE with the provided options %w", err)
 	}
@@ -193,45 +465,64 @@ func (c *CLIOpts) handleDeleteOptType(db database.DatabaseInteractions) error {
 }
 
 func displaySnippetList(snippets []models.CodeSnippet) {
+	// the library column is only shown when reading from more than one library
+	if slices.ContainsFunc(snippets, func(s models.CodeSnippet) bool { return s.Library != "" }) {
+		fmt.Printf("%-36s	%-25s	%-10s	%-20s	%-30s	%-12s\n", "Uuid", "Name", "Language", "Tags", "Description", "Library")
+		for _, s := range snippets {
+			fmt.Printf("%-36s	%-25s	%-10s	%-20s	%-30s	%-12s\n",
+				truncate(s.Uuid.String(), 36),
+				truncate(s.Name, 25),
+				truncate(s.Language, 10),
+				truncate(strings.Join(s.Tags, ","), 20),
+				truncate(s.Description, 30),
+				truncate(s.Library, 12),
+			)
+		}
+		return
+	}
+
 	fmt.Printf("%-36s	%-25s	%-10s	%-20s	%-30s	%-20s\n", "Uuid", "Name", "Language", "Tags", "Description", "Source")
 	for _, s := range snippets {
 		fmt.Printf("%-36s	%-25s	%-10s	%-20s	%-30s	%-20s\n",
 			truncate(s.Uuid.String(), 36),
 			truncate(s.Name, 25),
 			truncate(s.Language, 10),
-			truncate(s.Tags, 20),
+			truncate(strings.Join(s.Tags, ","), 20),
 			truncate(s.Description, 30),
 			truncate(s.Source, 20),
 		)
 	}
 }
 
-func displaySingleSnippet(snippet models.CodeSnippet, theme string) {
-	lexer := lexers.Get(snippet.Language)
-	if lexer == nil {
-		lexer = lexers.Fallback
+func displayTagList(tags []models.TagCount) {
+	fmt.Printf("%-30s	%s\n", "Tag", "Snippets")
+	for _, t := range tags {
+		fmt.Printf("%-30s	%d\n", t.Name, t.Count)
 	}
+}
+
+func (c *CLIOpts) displaySingleSnippet(snippet models.CodeSnippet) {
+	c.displayHighlighted(snippet.Code, snippet.Language)
 
-	style := styles.Get(theme)
+	if c.Clipboard {
+		_ = clipboard.WriteAll(snippet.Code)
+	}
+}
 
-	formatter := formatters.Get("terminal")
+// displayHighlighted prints code syntax highlighted for language using the configured theme and formatter
+func (c *CLIOpts) displayHighlighted(code string, language string) {
+	formatter := formatters.Get(c.Formatter)
 	if formatter == nil {
 		formatter = formatters.Fallback
 	}
-	iterator, err := lexer.Tokenise(nil, snippet.Code)
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	err = formatter.Format(os.Stdout, style, iterator)
+	err := highlightCode(os.Stdout, code, language, c.style(), formatter)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// add new line to the end otherwise it doesn't display properly
 	fmt.Println()
-
-	_ = clipboard.WriteAll(snippet.Code)
 }
 
 func truncate(s string, maxLength int) string {
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/Ryan-Har/csnip/config"
+)
+
+func (c *CLIOpts) handleConfigOptType() error {
+	switch c.OptType {
+	case OptTypeConfigList:
+		for _, key := range config.Keys() {
+			value, err := c.Config.Get(key)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s = %s\n", key, value)
+		}
+	case OptTypeConfigGet:
+		value, err := c.Config.Get(c.FlagOptions[FlagOptionConfigKey])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+	case OptTypeConfigSet:
+		// load the file directly so environment and flag overrides are not persisted
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		cfg, err := config.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Set(c.FlagOptions[FlagOptionConfigKey], c.FlagOptions[FlagOptionConfigValue]); err != nil {
+			return err
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Println("Config updated:", path)
+	}
+	return nil
+}
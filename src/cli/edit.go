@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelimiter = "---\n"
+
+// errEditCancelled is returned when the edited file is emptied
+var errEditCancelled = errors.New("edit cancelled, the snippet was not changed")
+
+// snippetFrontMatter is the metadata written at the top of a snippet being edited
+type snippetFrontMatter struct {
+	Name        string   `yaml:"name"`
+	Tags        []string `yaml:"tags,flow"`
+	Description string   `yaml:"description"`
+	Source      string   `yaml:"source"`
+}
+
+func (c *CLIOpts) handleEditOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+	if err != nil {
+		return fmt.Errorf("unable to parse provided UUID")
+	}
+	return EditSnippet(ctx, db, id)
+}
+
+// EditSnippet opens the snippet in the user's editor and stores a new version if it was changed
+func EditSnippet(ctx context.Context, db database.DatabaseInteractions, id uuid.UUID) error {
+	snippet, err := db.GetSnippetByUUID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve snippet: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "csnip-*"+languageExtension(snippet.Language))
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	content, err := renderSnippetFile(snippet)
+	if err != nil {
+		return err
+	}
+
+	// keep reopening the editor until the file parses or the edit is cancelled
+	var edited models.CodeSnippet
+	for {
+		if err := os.WriteFile(file.Name(), content, 0o600); err != nil {
+			return fmt.Errorf("unable to write temporary file: %w", err)
+		}
+		if err := runEditor(file.Name()); err != nil {
+			return err
+		}
+		content, err = os.ReadFile(file.Name())
+		if err != nil {
+			return fmt.Errorf("unable to read temporary file: %w", err)
+		}
+
+		edited, err = parseSnippetFile(content, snippet)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, errEditCancelled) {
+			return err
+		}
+		content = prependEditError(content, err)
+	}
+
+	if !snippetChanged(snippet, edited) {
+		fmt.Println("No changes made, the snippet was not updated")
+		return nil
+	}
+
+	updated, err := db.UpdateSnippet(ctx, id, edited)
+	if err != nil {
+		return fmt.Errorf("unable to update snippet: %w", err)
+	}
+	fmt.Printf("Code snippet updated to version %d\n", updated.Version)
+	return nil
+}
+
+// renderSnippetFile writes the snippet as a yaml front matter header followed by the code
+func renderSnippetFile(snippet models.CodeSnippet) ([]byte, error) {
+	header, err := yaml.Marshal(snippetFrontMatter{
+		Name:        snippet.Name,
+		Tags:        snippet.Tags,
+		Description: snippet.Description,
+		Source:      snippet.Source,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to write snippet header: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter)
+	fmt.Fprintf(&buf, "# Editing %s (%s), save and close the editor to update it.\n", snippet.Uuid, snippet.Language)
+	buf.WriteString("# Empty fields keep their current value, empty the whole file to cancel.\n")
+	buf.Write(header)
+	buf.WriteString(frontMatterDelimiter)
+	buf.WriteString(snippet.Code)
+	if !strings.HasSuffix(snippet.Code, "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// parseSnippetFile reads back a file written by renderSnippetFile, original is the snippet before editing
+func parseSnippetFile(content []byte, original models.CodeSnippet) (models.CodeSnippet, error) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	if strings.TrimSpace(text) == "" {
+		return models.CodeSnippet{}, errEditCancelled
+	}
+
+	// lines starting with # before the header are errors from a previous attempt
+	for strings.HasPrefix(text, "#") {
+		_, text, _ = strings.Cut(text, "\n")
+	}
+
+	if !strings.HasPrefix(text, frontMatterDelimiter) {
+		return models.CodeSnippet{}, fmt.Errorf("the file must start with a %q line", strings.TrimSpace(frontMatterDelimiter))
+	}
+	// the leading newline lets an empty header be closed straight away
+	header, code, found := strings.Cut("\n"+strings.TrimPrefix(text, frontMatterDelimiter), "\n"+frontMatterDelimiter)
+	if !found {
+		return models.CodeSnippet{}, fmt.Errorf("the header must end with a %q line", strings.TrimSpace(frontMatterDelimiter))
+	}
+
+	var fm snippetFrontMatter
+	dec := yaml.NewDecoder(strings.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fm); err != nil && !errors.Is(err, io.EOF) {
+		return models.CodeSnippet{}, fmt.Errorf("unable to read the header: %w", err)
+	}
+
+	// editors add a final newline, drop it again if the snippet did not have one
+	if !strings.HasSuffix(original.Code, "\n") {
+		code = strings.TrimSuffix(code, "\n")
+	}
+	if strings.TrimSpace(code) == "" {
+		return models.CodeSnippet{}, fmt.Errorf("the code must not be empty")
+	}
+
+	return models.CodeSnippet{
+		Uuid:        original.Uuid,
+		Name:        strings.TrimSpace(fm.Name),
+		Code:        code,
+		Language:    original.Language,
+		Tags:        common.NormaliseTags(fm.Tags),
+		Description: strings.TrimSpace(fm.Description),
+		Source:      strings.TrimSpace(fm.Source),
+	}, nil
+}
+
+// prependEditError adds the error as comments at the top of the file so it is shown when the editor reopens
+func prependEditError(content []byte, err error) []byte {
+	// drop the comments left from a previous attempt
+	text := string(content)
+	for strings.HasPrefix(text, "#") {
+		_, text, _ = strings.Cut(text, "\n")
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(&buf, "# error: %s\n", line)
+	}
+	buf.WriteString(text)
+	return buf.Bytes()
+}
+
+// snippetChanged reports whether an edit changed anything that would be stored.
+// Empty fields keep their current value when updating so they are not treated as a change.
+func snippetChanged(original, edited models.CodeSnippet) bool {
+	return edited.Code != original.Code ||
+		(edited.Name != "" && edited.Name != original.Name) ||
+		(len(edited.Tags) > 0 && !slices.Equal(edited.Tags, original.Tags)) ||
+		(edited.Description != "" && edited.Description != original.Description) ||
+		(edited.Source != "" && edited.Source != original.Source)
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may include arguments, such as "code --wait"
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to run editor %q: %w", editor, err)
+	}
+	return nil
+}
+
+// languageExtension returns a file extension for the language so editors can highlight the snippet
+func languageExtension(language string) string {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return ".txt"
+	}
+	for _, pattern := range lexer.Config().Filenames {
+		if ext := filepath.Ext(pattern); strings.HasPrefix(pattern, "*.") && ext != "" {
+			return ext
+		}
+	}
+	return ".txt"
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormats lists every value accepted by export --output
+var ExportFormats = []string{OutputJSON, OutputYAML, OutputVSCode}
+
+// ImportFormats lists every value accepted by import --input
+var ImportFormats = []string{OutputJSON, OutputYAML, OutputVSCode, InputPet, InputNavi, InputTldr, InputMarkdown}
+
+// the import formats of other snippet managers and cheat sheets
+const (
+	InputPet  = "pet"
+	InputNavi = "navi"
+	InputTldr = "tldr"
+)
+
+func (c *CLIOpts) handleExportOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
+	path := fOpts[FlagOptionPath]
+
+	filter, err := c.snippetFilter()
+	if err != nil {
+		return err
+	}
+	snippets, err := db.QuerySnippets(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve snippets to export: %w", err)
+	}
+
+	format := fOpts[FlagOptionOutput]
+	if format == "" {
+		format = formatFromExtension(path, OutputJSON)
+	}
+	if format == OutputVSCode {
+		if fOpts[FlagOptionHistory] != "" {
+			return fmt.Errorf("-with-history is only supported by the json and yaml formats")
+		}
+		return writeVSCodeExport(path, snippets)
+	}
+
+	export := models.Export{
+		Format:   models.ExportFormat,
+		Exported: time.Now().UTC().Truncate(time.Second),
+	}
+	for _, snippet := range snippets {
+		versions := []models.CodeSnippet{snippet}
+		if fOpts[FlagOptionHistory] != "" {
+			versions, err = db.GetSnippetHistoryByUUID(ctx, snippet.Uuid)
+			if err != nil {
+				return fmt.Errorf("unable to retrieve history of %s: %w", snippet.Uuid, err)
+			}
+			slices.Reverse(versions)
+		}
+		for _, version := range versions {
+			export.Snippets = append(export.Snippets, exportedSnippet(version))
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeExport(w, format, export); err != nil {
+		return fmt.Errorf("unable to write export: %w", err)
+	}
+	if w != os.Stdout {
+		fmt.Printf("Exported %d code snippets to %s\n", len(snippets), path)
+	}
+	return nil
+}
+
+// exportedSnippet clears the details that only make sense inside the database it was read from
+func exportedSnippet(snippet models.CodeSnippet) models.CodeSnippet {
+	snippet.ID = 0
+	snippet.SupersededBy = 0
+	snippet.DeletedAt = nil
+	snippet.Library = ""
+	return snippet
+}
+
+func writeExport(w io.Writer, format string, export models.Export) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(export)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(export); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown export format %q, expected one of %s", format, strings.Join(ExportFormats, ", "))
+}
+
+func (c *CLIOpts) handleImportOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
+	path := fOpts[FlagOptionPath]
+
+	strategy, err := database.ParseImportStrategy(fOpts[FlagOptionConflict])
+	if err != nil {
+		return err
+	}
+
+	format := fOpts[FlagOptionInput]
+	if format == "" {
+		format = formatFromExtension(path, "")
+	}
+
+	var snippets []models.CodeSnippet
+	_, foreign := foreignFormats[format]
+	var summary readSummary
+	if foreign {
+		snippets, summary, err = readForeignSnippets(path, format)
+	} else {
+		snippets, err = readExportFile(path, format)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read import: %w", err)
+	}
+
+	dryRun := fOpts[FlagOptionDryRun] != ""
+	result, err := db.ImportSnippets(ctx, snippets, database.ImportOptions{Strategy: strategy, DryRun: dryRun})
+	if err != nil {
+		return fmt.Errorf("unable to import snippets: %w", err)
+	}
+
+	if foreign {
+		displayReadSummary(summary)
+	}
+	displayImportResult(result, snippets, strategy, dryRun)
+	return nil
+}
+
+// readExportFile reads an export from a file, or stdin when path is -
+func readExportFile(path string, format string) ([]models.CodeSnippet, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readExport(data, format)
+}
+
+// readExport decodes an export document, or a plain list of snippets.
+// Without a format json is assumed when the data starts like json, otherwise yaml.
+func readExport(data []byte, format string) ([]models.CodeSnippet, error) {
+	if format == "" {
+		format = OutputYAML
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			format = OutputJSON
+		}
+	}
+
+	var export models.Export
+	switch format {
+	case OutputJSON:
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(data, &export.Snippets); err != nil {
+				return nil, err
+			}
+			return export.Snippets, nil
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, err
+		}
+	case OutputYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		if len(node.Content) > 0 && node.Content[0].Kind == yaml.SequenceNode {
+			if err := node.Decode(&export.Snippets); err != nil {
+				return nil, err
+			}
+			return export.Snippets, nil
+		}
+		if err := node.Decode(&export); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown import format %q, expected one of %s", format, strings.Join(ImportFormats, ", "))
+	}
+
+	if export.Format > models.ExportFormat {
+		return nil, fmt.Errorf("export format %d was written by a newer version of csnip", export.Format)
+	}
+	return export.Snippets, nil
+}
+
+// formatFromExtension maps a file extension to its format, returning fallback for any other file.
+// Markdown is left to the fallback, only some markdown files are tldr pages.
+func formatFromExtension(path string, fallback string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return OutputJSON
+	case ".yaml", ".yml":
+		return OutputYAML
+	case vscodeExtension:
+		return OutputVSCode
+	case ".toml":
+		return InputPet
+	case ".cheat":
+		return InputNavi
+	}
+	return fallback
+}
+
+func displayImportResult(result database.ImportResult, snippets []models.CodeSnippet, strategy database.ImportStrategy, dryRun bool) {
+	if dryRun {
+		fmt.Println("Dry run, no changes were made")
+	}
+	fmt.Printf("%d added, %d updated, %d overwritten, %d skipped, %d unchanged\n",
+		result.Added, result.Updated, result.Overwritten, result.Skipped, result.Unchanged)
+
+	if len(result.Conflicts) == 0 {
+		return
+	}
+	names := map[string]string{}
+	for _, snippet := range snippets {
+		names[snippet.Uuid.String()] = snippet.Name
+	}
+	fmt.Println("Already in the database with different content:")
+	for _, u := range result.Conflicts {
+		fmt.Printf("  %s  %s\n", u, names[u.String()])
+	}
+	if strategy == database.ImportSkip {
+		fmt.Println("Use -on-conflict overwrite or -on-conflict new-version to import them")
+	}
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/google/uuid"
+)
+
+// foreignReader reads the snippets from one file of another tool, name is the file's path within the import
+type foreignReader func(entries *importEntries, data []byte, name string) error
+
+// foreignFormats are the import formats of other tools and of markdown documents, with the extension of their files
+var foreignFormats = map[string]struct {
+	read       foreignReader
+	extensions []string
+}{
+	OutputVSCode:  {readVSCodeSnippets, []string{vscodeExtension, ".json"}},
+	InputPet:      {readPetSnippets, []string{".toml"}},
+	InputNavi:     {readNaviCheats, []string{".cheat"}},
+	InputTldr:     {readTldrPages, []string{".md"}},
+	InputMarkdown: {readMarkdownSnippets, []string{".md", ".markdown"}},
+}
+
+// importNamespace keeps the uuids given to entries read from other tools apart from the ones csnip generates
+var importNamespace = uuid.MustParse("6f1d3c4e-8a52-4b8e-9c1f-2d7a5e0b9c31")
+
+// importedUUID is the uuid for an entry read from another tool's file. The same entry always gets the same uuid,
+// so importing the file again finds the snippets it added before rather than adding them twice.
+func importedUUID(parts ...string) uuid.UUID {
+	return uuid.NewSHA1(importNamespace, []byte(strings.Join(parts, "\x00")))
+}
+
+// readSummary counts the entries read from other tools' files, and those that did not become snippets
+type readSummary struct {
+	Files      int
+	Entries    int
+	Skipped    int // entries with no code
+	Duplicates int // entries repeating an earlier one in the same import
+}
+
+// importEntries collects the snippets read from other tools' files, giving each its uuid
+type importEntries struct {
+	format   string
+	file     string // the file being read, recorded as the source of its snippets
+	snippets []models.CodeSnippet
+	codes    map[uuid.UUID]string
+	summary  readSummary
+}
+
+// add keeps snippet, its uuid made from key and the name of the file it was read from.
+// A key repeated in a file with different code also uses the code, the same key and code is a duplicate.
+func (e *importEntries) add(snippet models.CodeSnippet, name string, key string) {
+	e.summary.Entries++
+	if strings.TrimSpace(snippet.Code) == "" {
+		e.summary.Skipped++
+		return
+	}
+	if e.codes == nil {
+		e.codes = map[uuid.UUID]string{}
+	}
+
+	u := importedUUID(e.format, name, key)
+	if code, seen := e.codes[u]; seen {
+		if code == snippet.Code {
+			e.summary.Duplicates++
+			return
+		}
+		u = importedUUID(e.format, name, key, snippet.Code)
+		if _, seen := e.codes[u]; seen {
+			e.summary.Duplicates++
+			return
+		}
+	}
+	e.codes[u] = snippet.Code
+
+	snippet.Uuid = u
+	if snippet.Source == "" {
+		snippet.Source = e.file
+	}
+	e.snippets = append(e.snippets, snippet)
+}
+
+// readForeignSnippets reads the snippets from a file of another tool, or every file with the format's
+// extension in a directory. Use - as the path to read a single file from stdin.
+func readForeignSnippets(path string, format string) ([]models.CodeSnippet, readSummary, error) {
+	foreign := foreignFormats[format]
+	entries := importEntries{format: format}
+
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, entries.summary, err
+		}
+		entries.file = "stdin"
+		entries.summary.Files++
+		err = foreign.read(&entries, data, entries.file)
+		return entries.snippets, entries.summary, err
+	}
+
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return nil, entries.summary, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, entries.summary, err
+	}
+
+	readFile := func(file string, name string) error {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		entries.file = file
+		entries.summary.Files++
+		if err := foreign.read(&entries, data, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+
+	if !info.IsDir() {
+		err := readFile(root, filepath.Base(root))
+		return entries.snippets, entries.summary, err
+	}
+
+	// a file's name within the directory is used for its uuids, so they stay the same when the directory moves
+	err = filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !slices.Contains(foreign.extensions, strings.ToLower(filepath.Ext(file))) {
+			return nil
+		}
+		name, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		return readFile(file, filepath.ToSlash(name))
+	})
+	return entries.snippets, entries.summary, err
+}
+
+func displayReadSummary(summary readSummary) {
+	fmt.Printf("Read %d entries from %d files", summary.Entries, summary.Files)
+	if summary.Skipped > 0 {
+		fmt.Printf(", %d skipped with no code", summary.Skipped)
+	}
+	if summary.Duplicates > 0 {
+		fmt.Printf(", %d duplicates", summary.Duplicates)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+func (c *CLIOpts) handleGroupOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	name := c.FlagOptions[FlagOptionGroup]
+
+	switch c.OptType {
+	case OptTypeGroupCreate:
+		_, err := db.CreateGroup(ctx, name, c.FlagOptions[FlagOptionDescription])
+		if err != nil {
+			return fmt.Errorf("unable to create group %q: %w", name, err)
+		}
+		fmt.Println("Group created")
+	case OptTypeGroupList:
+		groups, err := db.GetGroups(ctx)
+		if err != nil {
+			return err
+		}
+		if c.FlagOptions[FlagOptionUUID] != "" {
+			id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+			if err != nil {
+				return fmt.Errorf("unable to parse provided UUID")
+			}
+			snippet, err := db.GetSnippetByUUID(ctx, id)
+			if err != nil {
+				return err
+			}
+			groups = slices.DeleteFunc(groups, func(g models.Group) bool {
+				return !slices.Contains(snippet.Groups, g.Name)
+			})
+		}
+		if len(groups) < 1 {
+			fmt.Println("No groups found")
+			return nil
+		}
+		displayGroupList(groups)
+	case OptTypeGroupShow:
+		group, err := db.GetGroupByName(ctx, name)
+		if err != nil {
+			return fmt.Errorf("unable to show group %q: %w", name, err)
+		}
+		snippets, err := db.GetSnippetsByGroup(ctx, name)
+		if err != nil {
+			return fmt.Errorf("unable to show group %q: %w", name, err)
+		}
+		fmt.Println("Group:      ", group.Name)
+		if group.Description != "" {
+			fmt.Println("Description:", group.Description)
+		}
+		fmt.Println()
+		if len(snippets) < 1 {
+			fmt.Println("No code snippets in group")
+			return nil
+		}
+		displaySnippetList(snippets)
+	case OptTypeGroupAdd:
+		id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+		if err != nil {
+			return fmt.Errorf("unable to parse provided UUID")
+		}
+		if err := db.AddSnippetToGroup(ctx, name, id); err != nil {
+			return fmt.Errorf("unable to add snippet to group %q: %w", name, err)
+		}
+		fmt.Println("Code snippet added to group")
+	case OptTypeGroupRemove:
+		id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+		if err != nil {
+			return fmt.Errorf("unable to parse provided UUID")
+		}
+		if err := db.RemoveSnippetFromGroup(ctx, name, id); err != nil {
+			return fmt.Errorf("unable to remove snippet from group %q: %w", name, err)
+		}
+		fmt.Println("Code snippet removed from group")
+	case OptTypeGroupDelete:
+		if err := db.DeleteGroup(ctx, name); err != nil {
+			return fmt.Errorf("unable to delete group %q: %w", name, err)
+		}
+		fmt.Println("Group deleted")
+	}
+	return nil
+}
+
+func displayGroupList(groups []models.Group) {
+	fmt.Printf("%-25s	%-8s	%-40s\n", "Name", "Snippets", "Description")
+	for _, g := range groups {
+		fmt.Printf("%-25s	%-8d	%-40s\n",
+			truncate(g.Name, 25),
+			len(g.Snippets),
+			truncate(g.Description, 40),
+		)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/Ryan-Har/csnip/diff"
+	"github.com/google/uuid"
+)
+
+func (c *CLIOpts) handleHistoryOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+	if err != nil {
+		return fmt.Errorf("unable to parse provided UUID")
+	}
+
+	switch c.OptType {
+	case OptTypeHistory:
+		versions, err := db.GetSnippetHistoryByUUID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve snippet history: %w", err)
+		}
+		if len(versions) < 1 {
+			return database.ErrNoSnippetsFound
+		}
+		slices.SortFunc(versions, func(a, b models.CodeSnippet) int {
+			return int(a.Version - b.Version)
+		})
+		if c.structuredOutput() {
+			return writeItems(c, os.Stdout, versions, false, snippetColumns, snippetRow)
+		}
+		displaySnippetHistory(versions)
+	case OptTypeRevert:
+		version, err := parseVersion(c.FlagOptions[FlagOptionVersion])
+		if err != nil || version == 0 {
+			return fmt.Errorf("invalid version supplied: %v", c.FlagOptions[FlagOptionVersion])
+		}
+		reverted, err := db.RevertSnippet(ctx, id, version)
+		if err != nil {
+			return fmt.Errorf("unable to revert snippet to version %d: %w", version, err)
+		}
+		fmt.Printf("Code snippet reverted to version %d, saved as version %d\n", version, reverted.Version)
+	case OptTypeDiff:
+		from, err := parseVersion(c.FlagOptions[FlagOptionFromVersion])
+		if err != nil {
+			return err
+		}
+		to, err := parseVersion(c.FlagOptions[FlagOptionVersion])
+		if err != nil {
+			return err
+		}
+		d, err := diff.DiffVersions(ctx, db, id, from, to)
+		if err != nil {
+			return fmt.Errorf("unable to compare snippet versions: %w", err)
+		}
+		if !d.Changed() {
+			fmt.Printf("No differences between version %d and version %d\n", d.From.Version, d.To.Version)
+			return nil
+		}
+		c.displayHighlighted(strings.TrimSuffix(d.Unified(3), "\n"), "diff")
+	}
+	return nil
+}
+
+// displaySnippetHistory prints versions, which are sorted oldest first, along with the fields each one changed
+func displaySnippetHistory(versions []models.CodeSnippet) {
+	fmt.Printf("%-8s	%-20s	%-40s\n", "Version", "Date", "Changed")
+	for i, v := range versions {
+		changed := "created"
+		if i > 0 {
+			changed = strings.Join(changedFields(versions[i-1], v), ", ")
+			if changed == "" {
+				changed = "no changes"
+			}
+		}
+		fmt.Printf("%-8d	%-20s	%-40s\n",
+			v.Version,
+			v.DateAdded.Local().Format(time.DateTime),
+			changed,
+		)
+	}
+}
+
+// changedFields lists the names of the user editable fields that differ between two versions of a snippet
+func changedFields(prev, cur models.CodeSnippet) []string {
+	var changed []string
+	if prev.Code != cur.Code {
+		changed = append(changed, "code")
+	}
+	for _, f := range diff.ChangedFields(prev, cur) {
+		changed = append(changed, f.Field)
+	}
+	return changed
+}
+
+// parseVersion parses a version number flag, an empty flag is returned as zero
+func parseVersion(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version supplied: %v", s)
+	}
+	return version, nil
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common"
+)
+
+// resolveLanguage returns the canonical name of lang, or detects the language from the file the code
+// was read from or the code itself when no language was given
+func resolveLanguage(lang string, file string, code string) (string, error) {
+	if lang != "" {
+		return canonicalLanguage(lang)
+	}
+	detected, ok := common.DetectLanguage(file, code)
+	if !ok {
+		return "", fmt.Errorf("unable to detect the language of the snippet, please provide one with -l")
+	}
+	fmt.Printf("Detected language %s\n", detected)
+	return detected, nil
+}
+
+// canonicalLanguage returns the canonical name of lang, suggesting similar languages when it is unknown
+func canonicalLanguage(lang string) (string, error) {
+	canonical, ok := common.CanonicalLanguage(lang)
+	if ok {
+		return canonical, nil
+	}
+	if suggestions := common.SuggestLanguages(lang); len(suggestions) > 0 {
+		return "", fmt.Errorf("unknown language %q, did you mean %s?", lang, strings.Join(suggestions, ", "))
+	}
+	return "", fmt.Errorf("unknown language %q", lang)
+}
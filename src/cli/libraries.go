@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/config"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+func (c *CLIOpts) handleCopyToOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	libraries, ok := db.(database.LibraryInteractions)
+	if !ok {
+		return fmt.Errorf("no libraries are configured, add one with: csnip library add -n <name> -p <path>")
+	}
+
+	id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+	if err != nil {
+		return fmt.Errorf("unable to parse provided UUID")
+	}
+
+	library := c.FlagOptions[FlagOptionLibrary]
+	copied, err := libraries.CopySnippetTo(ctx, id, library)
+	if err != nil {
+		return fmt.Errorf("unable to copy snippet to %s: %w", library, err)
+	}
+	fmt.Printf("Code snippet copied to %s as %s\n", copied.Library, copied.Uuid)
+	return nil
+}
+
+func (c *CLIOpts) handleLibraryOptType() error {
+	switch c.OptType {
+	case OptTypeLibraryList:
+		displayLibraryList(c.Config)
+		return nil
+	}
+
+	// load the file directly so environment and flag overrides are not persisted
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	name := c.FlagOptions[FlagOptionLibrary]
+	switch c.OptType {
+	case OptTypeLibraryAdd:
+		if err := cfg.AddLibrary(name, c.FlagOptions[FlagOptionPath]); err != nil {
+			return err
+		}
+		// create the library if it does not exist yet so a new shared library can be started
+		libraryPath := cfg.Libraries[len(cfg.Libraries)-1].Path
+		if _, err := os.Stat(libraryPath); errors.Is(err, os.ErrNotExist) {
+			db, err := database.NewSQLiteHandler(libraryPath)
+			if err != nil {
+				return fmt.Errorf("unable to create library %s: %w", name, err)
+			}
+			db.Close()
+			fmt.Println("Created library at", libraryPath)
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Println("Library added")
+	case OptTypeLibraryRemove:
+		if err := cfg.RemoveLibrary(name); err != nil {
+			return err
+		}
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Println("Library removed, its database was left in place")
+	}
+	return nil
+}
+
+func displayLibraryList(cfg config.Config) {
+	writable := cfg.WriteLibrary
+	if writable == "" {
+		writable = config.PersonalLibrary
+	}
+
+	libraries := append([]config.Library{{Name: config.PersonalLibrary, Path: cfg.Database}}, cfg.Libraries...)
+	fmt.Printf("%-20s	%-8s	%s\n", "Name", "Writes", "Path")
+	for _, l := range libraries {
+		writes := ""
+		if strings.EqualFold(l.Name, writable) {
+			writes = "yes"
+		}
+		fmt.Printf("%-20s	%-8s	%s\n", truncate(l.Name, 20), writes, l.Path)
+	}
+}
@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+// InputMarkdown is the import format of markdown documents, each fenced code block is a snippet
+const InputMarkdown = "markdown"
+
+// readMarkdownSnippets turns each fenced code block of a markdown document into a snippet. The language comes from
+// the block's info string, the name from the nearest heading above it and the description from the paragraph
+// directly before it. The source is the file and the heading's anchor, as file#heading.
+func readMarkdownSnippets(entries *importEntries, data []byte, name string) error {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	anchors := markdownAnchors{}
+	heading, anchor := "", ""
+	blocks := 0 // blocks under the current heading, so each has a key that stays the same between imports
+	var paragraph []string
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence, info, ok := markdownFence(line); ok {
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			var code []string
+			for i++; i < len(lines); i++ {
+				if closing, closingInfo, ok := markdownFence(lines[i]); ok && closingInfo == "" &&
+					closing[0] == fence[0] && len(closing) >= len(fence) {
+					break
+				}
+				code = append(code, trimIndent(lines[i], indent))
+			}
+
+			blocks++
+			snippet := models.CodeSnippet{
+				Name:        heading,
+				Code:        strings.Join(code, "\n"),
+				Language:    markdownLanguage(info),
+				Description: strings.Join(paragraph, " "),
+				Source:      entries.file,
+			}
+			if anchor != "" {
+				snippet.Source += "#" + anchor
+			}
+			if snippet.Name == "" {
+				snippet.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+			}
+			entries.add(snippet, name, anchor+"#"+strconv.Itoa(blocks))
+			paragraph = nil
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			// a blank line ends a paragraph, it is kept until text after it starts the next one
+		case strings.HasPrefix(trimmed, "#") && markdownHeadingLevel(trimmed) > 0:
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			// a closing run of #s is not part of the heading
+			if end := strings.LastIndex(heading, " #"); end >= 0 && strings.Trim(heading[end:], " #") == "" {
+				heading = strings.TrimSpace(heading[:end])
+			}
+			anchor = anchors.add(heading)
+			blocks = 0
+			paragraph = nil
+		default:
+			if i > 0 && strings.TrimSpace(lines[i-1]) == "" {
+				paragraph = nil
+			}
+			// a backslash before what would start a heading or a code block is there to make it text
+			if strings.HasPrefix(trimmed, `\`) && markdownNeedsEscape(trimmed[1:]) {
+				trimmed = trimmed[1:]
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	return nil
+}
+
+// markdownFence returns the fence and info string of a line opening or closing a fenced code block
+func markdownFence(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return "", "", false
+	}
+	info := strings.TrimSpace(trimmed[n:])
+	// the info string of a backtick fence cannot contain a backtick
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return trimmed[:n], info, true
+}
+
+// markdownHeadingLevel is the level of an atx heading, or 0 when the line is not a heading
+func markdownHeadingLevel(line string) int {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level > 6 || (len(line) > level && line[level] != ' ' && line[level] != '\t') {
+		return 0
+	}
+	return level
+}
+
+// markdownLanguage is the language named by the first word of a code block's info string, or plain text
+func markdownLanguage(info string) string {
+	if fields := strings.Fields(info); len(fields) > 0 {
+		// attributes such as {.python} are allowed by some renderers
+		lang := strings.Trim(fields[0], "{}.")
+		if canonical, ok := common.CanonicalLanguage(lang); ok {
+			return canonical
+		}
+	}
+	return "plaintext"
+}
+
+// trimIndent removes up to indent leading spaces or tabs, the indentation of the fence that opened the block
+func trimIndent(line string, indent int) string {
+	i := 0
+	for i < indent && i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return line[i:]
+}
+
+// markdownAnchors makes the anchors GitHub gives headings, a repeated heading gets a numbered suffix
+type markdownAnchors map[string]int
+
+func (a markdownAnchors) add(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	anchor := b.String()
+	if n := a[anchor]; n > 0 {
+		a[anchor] = n + 1
+		return fmt.Sprintf("%s-%d", anchor, n)
+	}
+	a[anchor] = 1
+	return anchor
+}
+
+func (c *CLIOpts) handleExportMarkdownOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
+	path := fOpts[FlagOptionPath]
+
+	filter, err := c.snippetFilter()
+	if err != nil {
+		return err
+	}
+	snippets, err := db.QuerySnippets(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve snippets to export: %w", err)
+	}
+
+	title := "Snippets"
+	description := ""
+	if filter.Group != "" {
+		group, err := db.GetGroupByName(ctx, filter.Group)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve group %s: %w", filter.Group, err)
+		}
+		title, description = group.Name, group.Description
+
+		// a group's cheat sheet keeps the group's order
+		members, err := db.GetSnippetsByGroup(ctx, filter.Group)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve snippets in group %s: %w", filter.Group, err)
+		}
+		matched := map[uuid.UUID]bool{}
+		for _, snippet := range snippets {
+			matched[snippet.Uuid] = true
+		}
+		snippets = snippets[:0]
+		for _, member := range members {
+			if matched[member.Uuid] {
+				snippets = append(snippets, member)
+			}
+		}
+	}
+	if t := fOpts[FlagOptionTitle]; t != "" {
+		title = t
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeMarkdown(w, title, description, snippets); err != nil {
+		return fmt.Errorf("unable to write export: %w", err)
+	}
+	if w != os.Stdout {
+		fmt.Printf("Exported %d code snippets to %s\n", len(snippets), path)
+	}
+	return nil
+}
+
+// writeMarkdown renders snippets as a cheat sheet, a table of contents followed by a section for each snippet.
+// Descriptions are written as one paragraph with their whitespace collapsed, as import-md joins the lines of a
+// paragraph and only keeps the last one, so reading the cheat sheet back gives the same names, languages and
+// descriptions. Trailing newlines of the code are not kept.
+func writeMarkdown(w io.Writer, title string, description string, snippets []models.CodeSnippet) error {
+	var b strings.Builder
+	b.WriteString("# " + title + "\n\n")
+	if description = markdownParagraph(description); description != "" {
+		b.WriteString(description + "\n\n")
+	}
+
+	anchors := markdownAnchors{}
+	anchors.add(title)
+	anchors.add("Contents")
+	headings := make([]string, len(snippets))
+	b.WriteString("## Contents\n\n")
+	for i, snippet := range snippets {
+		headings[i] = snippet.Name
+		if headings[i] == "" {
+			headings[i] = snippet.Uuid.String()
+		}
+		headings[i] = strings.ReplaceAll(headings[i], "\n", " ")
+		fmt.Fprintf(&b, "- [%s](#%s)\n", escapeMarkdown(headings[i]), anchors.add(headings[i]))
+	}
+
+	for i, snippet := range snippets {
+		b.WriteString("\n## " + headings[i] + "\n\n")
+		if description := markdownParagraph(snippet.Description); description != "" {
+			b.WriteString(description + "\n\n")
+		}
+
+		// the fence is longer than any run of backticks in the code so it cannot close the block early
+		fence := "```"
+		for strings.Contains(snippet.Code, fence) {
+			fence += "`"
+		}
+		info := snippet.Language
+		if info == "plaintext" {
+			info = "text"
+		}
+		b.WriteString(fence + info + "\n" + strings.TrimRight(snippet.Code, "\n") + "\n" + fence + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownParagraph collapses text onto a single line and escapes a start that would read as a heading or a fence
+func markdownParagraph(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if markdownNeedsEscape(text) {
+		return `\` + text
+	}
+	return text
+}
+
+// markdownNeedsEscape reports whether a line of text needs a backslash in front to be read as text, because it
+// would start a heading or a code block, or starts with a backslash that would be taken as such an escape
+func markdownNeedsEscape(line string) bool {
+	if _, _, ok := markdownFence(line); ok {
+		return true
+	}
+	if strings.HasPrefix(line, "#") && markdownHeadingLevel(line) > 0 {
+		return true
+	}
+	return strings.HasPrefix(line, `\`) && markdownNeedsEscape(line[1:])
+}
+
+var markdownEscaper = strings.NewReplacer(`[`, `\[`, `]`, `\]`)
+
+// escapeMarkdown escapes the brackets that would end a link's text early
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
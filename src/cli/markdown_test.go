@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+// TestMarkdownRoundTrip writes snippets as a cheat sheet and reads them back with the import-md reader
+func TestMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		snippet     models.CodeSnippet
+		description string // the description read back, when it differs from the one written
+	}{
+		{name: "simple", snippet: models.CodeSnippet{Name: "serve", Code: "http.ListenAndServe(\":8080\", nil)", Language: "go", Description: "Start a server"}},
+		{name: "no description", snippet: models.CodeSnippet{Name: "list", Code: "ls -la", Language: "bash"}},
+		{name: "plain text", snippet: models.CodeSnippet{Name: "notes", Code: "just text", Language: "plaintext"}},
+		{name: "repeated name", snippet: models.CodeSnippet{Name: "serve", Code: "python -m http.server", Language: "python"}},
+		{name: "brackets in name", snippet: models.CodeSnippet{Name: "index [i]", Code: "xs[i]", Language: "go"}},
+		{name: "fence in code", snippet: models.CodeSnippet{Name: "readme", Code: "```go\nx := 1\n```", Language: "markdown"}},
+		{name: "multi-line code", snippet: models.CodeSnippet{Name: "loop", Code: "for {\n\tbreak\n}", Language: "go"}},
+		{
+			name:        "multi-paragraph description",
+			snippet:     models.CodeSnippet{Name: "para", Code: "a", Language: "go", Description: "First paragraph\nover two lines.\n\nSecond  paragraph."},
+			description: "First paragraph over two lines. Second paragraph.",
+		},
+		{name: "heading description", snippet: models.CodeSnippet{Name: "tag", Code: "b", Language: "go", Description: "# not a heading"}},
+		{name: "hash only description", snippet: models.CodeSnippet{Name: "hash", Code: "c", Language: "go", Description: "#"}},
+		{name: "fence description", snippet: models.CodeSnippet{Name: "fence", Code: "d", Language: "go", Description: "```not code"}},
+		{name: "escaped description", snippet: models.CodeSnippet{Name: "escaped", Code: "e", Language: "go", Description: `\# stays escaped`}},
+		{name: "hashtag description", snippet: models.CodeSnippet{Name: "hashtag", Code: "f", Language: "go", Description: "#go is not a heading"}},
+	}
+
+	snippets := make([]models.CodeSnippet, len(tests))
+	for i, tt := range tests {
+		snippets[i] = tt.snippet
+	}
+	var b strings.Builder
+	if err := writeMarkdown(&b, "Cheat sheet", "Group\n\n# description", snippets); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := importEntries{format: InputMarkdown, file: "cheat.md"}
+	if err := readMarkdownSnippets(&entries, []byte(b.String()), "cheat.md"); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries.snippets) != len(tests) {
+		t.Fatalf("read back %d snippets, want %d from:\n%s", len(entries.snippets), len(tests), b.String())
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := entries.snippets[i], tt.snippet
+			if tt.description != "" {
+				want.Description = tt.description
+			}
+			if got.Name != want.Name || got.Code != want.Code || got.Language != want.Language || got.Description != want.Description {
+				t.Errorf("read back as %+v, want %+v", got, want)
+			}
+		})
+	}
+}
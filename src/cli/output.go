@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"gopkg.in/yaml.v3"
+)
+
+// output formats accepted by --output, table is the human readable default
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+	OutputTSV   = "tsv"
+)
+
+// OutputFormats lists every value accepted by --output
+var OutputFormats = []string{OutputTable, OutputJSON, OutputYAML, OutputCSV, OutputTSV}
+
+// structuredOutput reports whether results should be written for scripts rather than as a table
+func (c *CLIOpts) structuredOutput() bool {
+	output := c.FlagOptions[FlagOptionOutput]
+	return c.FlagOptions[FlagOptionFormat] != "" || (output != "" && output != OutputTable)
+}
+
+// writeItems writes items using the --format template if one was given, otherwise in the --output format.
+// A single item is written on its own rather than as a list in json and yaml.
+// columns and row are used for csv and tsv output.
+func writeItems[T any](c *CLIOpts, w io.Writer, items []T, single bool, columns []string, row func(T) []string) error {
+	if format := c.FlagOptions[FlagOptionFormat]; format != "" {
+		return writeTemplate(w, format, items)
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+
+	var value interface{} = items
+	if single && len(items) == 1 {
+		value = items[0]
+	}
+
+	switch c.FlagOptions[FlagOptionOutput] {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(value); err != nil {
+			return err
+		}
+		return enc.Close()
+	case OutputCSV, OutputTSV:
+		cw := csv.NewWriter(w)
+		if c.FlagOptions[FlagOptionOutput] == OutputTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := cw.Write(row(item)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %s", c.FlagOptions[FlagOptionOutput], strings.Join(OutputFormats, ", "))
+}
+
+// writeTemplate executes the Go template once for every item, each on its own line
+func writeTemplate[T any](w io.Writer, format string, items []T) error {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid format template: %w", err)
+	}
+
+	for _, item := range items {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snippetColumns are the csv and tsv columns written for a snippet
+var snippetColumns = []string{"uuid", "version", "name", "language", "tags", "description", "source", "date_added", "code", "library"}
+
+func snippetRow(s models.CodeSnippet) []string {
+	return []string{
+		s.Uuid.String(),
+		strconv.FormatInt(s.Version, 10),
+		s.Name,
+		s.Language,
+		strings.Join(s.Tags, ","),
+		s.Description,
+		s.Source,
+		s.DateAdded.Format(time.RFC3339),
+		s.Code,
+		s.Library,
+	}
+}
+
+var searchResultColumns = append(append([]string{}, snippetColumns...), "rank")
+
+func searchResultRow(r models.SearchResult) []string {
+	return append(snippetRow(r.CodeSnippet), strconv.FormatFloat(r.Rank, 'f', -1, 64))
+}
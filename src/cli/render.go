@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/google/uuid"
+)
+
+// formats accepted by show --format
+const (
+	RenderHTML  = "html"
+	RenderSVG   = "svg"
+	RenderANSI  = "ansi"
+	RenderPlain = "plain"
+)
+
+// RenderFormats lists every value accepted by show --format
+var RenderFormats = []string{RenderHTML, RenderSVG, RenderANSI, RenderPlain}
+
+func (c *CLIOpts) handleShowOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
+	id, err := uuid.Parse(fOpts[FlagOptionUUID])
+	if err != nil {
+		return fmt.Errorf("unable to parse provided UUID")
+	}
+
+	snippet, err := db.GetSnippetByUUID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve snippet: %w", err)
+	}
+
+	formatter, err := c.renderFormatter(fOpts[FlagOptionRender])
+	if err != nil {
+		return err
+	}
+
+	path := fOpts[FlagOptionPath]
+	var w io.Writer = os.Stdout
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := highlightCode(w, snippet.Code, snippet.Language, c.style(), formatter); err != nil {
+		return fmt.Errorf("unable to render snippet: %w", err)
+	}
+	// terminals need the final line ended to display it properly, files are written as the formatter produced them
+	if w == os.Stdout && !strings.HasSuffix(snippet.Code, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+// renderFormatter is the chroma formatter for a show format. ansi uses the configured formatter
+// when it is one of chroma's terminal formatters, so the output matches get.
+func (c *CLIOpts) renderFormatter(format string) (chroma.Formatter, error) {
+	switch format {
+	case RenderHTML:
+		return formatters.Get("html"), nil
+	case RenderSVG:
+		return formatters.SVG, nil
+	case RenderPlain:
+		return formatters.NoOp, nil
+	case RenderANSI, "":
+		if strings.HasPrefix(c.Formatter, "terminal") {
+			return formatters.Get(c.Formatter), nil
+		}
+		return formatters.Get("terminal"), nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(RenderFormats, ", "))
+}
+
+// style is the configured theme, or chroma's fallback when the theme is unknown
+func (c *CLIOpts) style() *chroma.Style {
+	style := styles.Get(c.Theme)
+	if style == nil {
+		style = styles.Fallback
+	}
+	return style
+}
+
+// highlightCode writes code highlighted for language, falling back to plain text for an unknown language
+func highlightCode(w io.Writer, code string, language string, style *chroma.Style, formatter chroma.Formatter) error {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+	return formatter.Format(w, style, iterator)
+}
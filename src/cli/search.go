@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+)
+
+const (
+	ansiHighlightStart = "\033[1;33m"
+	ansiHighlightEnd   = "\033[0m"
+)
+
+func (c *CLIOpts) handleSearchOptType(ctx context.Context, db database.DatabaseInteractions) ([]models.SearchResult, error) {
+	opts := database.SearchOptions{
+		Language: c.FlagOptions[FlagOptionLanguage],
+		Limit:    c.PageSize,
+	}
+
+	if limit, ok := c.FlagOptions[FlagOptionLimit]; ok {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse provided limit")
+		}
+		opts.Limit = l
+	}
+
+	// only colour the matches when writing a table to a terminal
+	if isTerminal(os.Stdout) && !c.structuredOutput() {
+		opts.HighlightStart = ansiHighlightStart
+		opts.HighlightEnd = ansiHighlightEnd
+	}
+
+	return db.Search(ctx, c.FlagOptions[FlagOptionQuery], opts)
+}
+
+func displaySearchResults(results []models.SearchResult) {
+	for _, r := range results {
+		name := r.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Printf("%s  %s [%s]\n", r.Uuid.String(), name, r.Language)
+		for _, f := range r.Fragments {
+			// fragments of code can span several lines, collapse them to keep one line per match
+			fmt.Printf("    %s\n", strings.Join(strings.Fields(f), " "))
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+)
+
+// siteLink is a link to a language or tag page with the number of snippets on it
+type siteLink struct {
+	Name  string
+	Path  string
+	Count int
+}
+
+// siteSnippet is a snippet as it is listed and shown on the site
+type siteSnippet struct {
+	models.CodeSnippet
+	Path     string
+	Search   string // lower case text the index search matches against
+	Language siteLink
+	Tags     []siteLink
+	Code     template.HTML
+	History  []siteVersion
+}
+
+// siteVersion is an earlier version of a snippet shown on its page
+type siteVersion struct {
+	models.CodeSnippet
+	Code template.HTML
+}
+
+// sitePage is the data given to every page template, Root leads from the page back to the top of the site
+type sitePage struct {
+	Title     string
+	Root      string
+	Snippets  []*siteSnippet
+	Snippet   *siteSnippet
+	Languages []siteLink
+	Tags      []siteLink
+}
+
+func (c *CLIOpts) handleSiteOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	fOpts := c.FlagOptions
+	dir := fOpts[FlagOptionPath]
+	title := cmp.Or(fOpts[FlagOptionTitle], "Snippets")
+
+	snippets, err := db.QuerySnippets(ctx, database.SnippetFilter{})
+	if err != nil {
+		return fmt.Errorf("unable to retrieve snippets: %w", err)
+	}
+	slices.SortStableFunc(snippets, func(a, b models.CodeSnippet) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	style := c.style()
+	formatter := html.New(html.WithClasses(true))
+	highlight := func(code string, language string) (template.HTML, error) {
+		var b bytes.Buffer
+		if err := highlightCode(&b, code, language, style, formatter); err != nil {
+			return "", err
+		}
+		return template.HTML(b.String()), nil
+	}
+
+	languageSlugs, tagSlugs := siteSlugs{}, siteSlugs{}
+	languages, tags := map[string]*siteLink{}, map[string]*siteLink{}
+	link := func(links map[string]*siteLink, slugs siteSlugs, dir string, name string) siteLink {
+		if links[name] == nil {
+			links[name] = &siteLink{Name: name, Path: dir + "/" + slugs.add(name) + ".html"}
+		}
+		links[name].Count++
+		return *links[name]
+	}
+
+	pages := make([]*siteSnippet, 0, len(snippets))
+	for _, snippet := range snippets {
+		page := &siteSnippet{
+			CodeSnippet: snippet,
+			Path:        "snippet/" + snippet.Uuid.String() + ".html",
+			Language:    link(languages, languageSlugs, "language", snippet.Language),
+		}
+		for _, tag := range snippet.Tags {
+			page.Tags = append(page.Tags, link(tags, tagSlugs, "tag", tag))
+		}
+		page.Search = strings.ToLower(strings.Join(append([]string{snippet.Name, snippet.Description, snippet.Language, snippet.Source}, snippet.Tags...), " "))
+
+		if page.Code, err = highlight(snippet.Code, snippet.Language); err != nil {
+			return fmt.Errorf("unable to highlight %s: %w", snippet.Uuid, err)
+		}
+
+		versions, err := db.GetSnippetHistoryByUUID(ctx, snippet.Uuid)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve history of %s: %w", snippet.Uuid, err)
+		}
+		slices.SortFunc(versions, func(a, b models.CodeSnippet) int {
+			return cmp.Compare(b.Version, a.Version)
+		})
+		for _, version := range versions {
+			if version.Version == snippet.Version {
+				continue
+			}
+			code, err := highlight(version.Code, version.Language)
+			if err != nil {
+				return fmt.Errorf("unable to highlight %s version %d: %w", snippet.Uuid, version.Version, err)
+			}
+			page.History = append(page.History, siteVersion{CodeSnippet: version, Code: code})
+		}
+		pages = append(pages, page)
+	}
+
+	// counts are only complete once every snippet has been read, so links are taken from the maps again
+	sortedLinks := func(links map[string]*siteLink) []siteLink {
+		var sorted []siteLink
+		for _, l := range links {
+			sorted = append(sorted, *l)
+		}
+		slices.SortFunc(sorted, func(a, b siteLink) int { return cmp.Compare(a.Name, b.Name) })
+		return sorted
+	}
+	index := sitePage{
+		Title:     title,
+		Snippets:  pages,
+		Languages: sortedLinks(languages),
+		Tags:      sortedLinks(tags),
+	}
+
+	files := map[string][]byte{}
+	write := func(path string, tmpl string, page sitePage) error {
+		var b bytes.Buffer
+		if err := siteTemplates.ExecuteTemplate(&b, tmpl, page); err != nil {
+			return fmt.Errorf("unable to render %s: %w", path, err)
+		}
+		files[path] = b.Bytes()
+		return nil
+	}
+
+	if err := write("index.html", "index", index); err != nil {
+		return err
+	}
+	for _, l := range index.Languages {
+		page := sitePage{Title: l.Name, Root: "../", Snippets: filterSitePages(pages, func(s *siteSnippet) bool { return s.Language.Name == l.Name })}
+		if err := write(l.Path, "list", page); err != nil {
+			return err
+		}
+	}
+	for _, l := range index.Tags {
+		page := sitePage{Title: "#" + l.Name, Root: "../", Snippets: filterSitePages(pages, func(s *siteSnippet) bool {
+			return slices.ContainsFunc(s.Tags, func(t siteLink) bool { return t.Name == l.Name })
+		})}
+		if err := write(l.Path, "list", page); err != nil {
+			return err
+		}
+	}
+	for _, p := range pages {
+		if err := write(p.Path, "snippet", sitePage{Title: cmp.Or(p.Name, p.Uuid.String()), Root: "../", Snippet: p}); err != nil {
+			return err
+		}
+	}
+
+	var css bytes.Buffer
+	css.WriteString(siteStylesheet(style))
+	if err := formatter.WriteCSS(&css, style); err != nil {
+		return fmt.Errorf("unable to write stylesheet: %w", err)
+	}
+	files["style.css"] = css.Bytes()
+
+	for path, data := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("unable to create site directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("unable to write site: %w", err)
+		}
+	}
+
+	fmt.Printf("Generated a site of %d code snippets in %s\n", len(pages), dir)
+	return nil
+}
+
+func filterSitePages(pages []*siteSnippet, keep func(*siteSnippet) bool) []*siteSnippet {
+	var kept []*siteSnippet
+	for _, p := range pages {
+		if keep(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// siteSlugs makes file names for language and tag pages, a name that would clash with an earlier one gets a number.
+// It holds every slug handed out.
+type siteSlugs map[string]bool
+
+var siteSlugReplacer = strings.NewReplacer("+", "plus", "#", "sharp")
+
+func (s siteSlugs) add(name string) string {
+	var b strings.Builder
+	for _, r := range siteSlugReplacer.Replace(strings.ToLower(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	slug := strings.Trim(b.String(), "-.")
+	if slug == "" {
+		slug = "unnamed"
+	}
+	// a numbered slug can itself be taken, by a name such as foo-1
+	unique := slug
+	for n := 1; s[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", slug, n)
+	}
+	s[unique] = true
+	return unique
+}
+
+// siteStylesheet lays out the site in the colours of the theme
+func siteStylesheet(style *chroma.Style) string {
+	background, foreground := "#ffffff", "#1f2328"
+	if entry := style.Get(chroma.Background); entry.Background.IsSet() {
+		background = entry.Background.String()
+		if entry.Colour.IsSet() {
+			foreground = entry.Colour.String()
+		}
+	}
+	return fmt.Sprintf(`body { background: %s; color: %s; font-family: system-ui, sans-serif; max-width: 60rem; margin: 0 auto; padding: 1rem; }
+a { color: inherit; }
+header { display: flex; gap: 1rem; align-items: baseline; }
+input[type=search] { width: 100%%; padding: .5rem; font-size: 1rem; box-sizing: border-box; }
+table { width: 100%%; border-collapse: collapse; }
+td, th { text-align: left; padding: .3rem .5rem; border-bottom: 1px solid color-mix(in srgb, currentColor 20%%, transparent); vertical-align: top; }
+.links a { display: inline-block; margin: 0 .6rem .3rem 0; }
+.meta { opacity: .8; }
+pre { padding: 1rem; overflow-x: auto; border-radius: 4px; }
+details { margin: 1rem 0; }
+`, background, foreground)
+}
+
+var siteTemplates = template.Must(template.New("site").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<header><h1>{{.Title}}</h1>{{if .Root}}<a href="{{.Root}}index.html">all snippets</a>{{end}}</header>
+{{end}}
+
+{{define "table"}}<table id="snippets">
+<thead><tr><th>Name</th><th>Language</th><th>Tags</th><th>Description</th></tr></thead>
+<tbody>
+{{range .Snippets}}<tr data-search="{{.Search}}">
+<td><a href="{{$.Root}}{{.Path}}">{{or .Name .Uuid}}</a></td>
+<td><a href="{{$.Root}}{{.Language.Path}}">{{.Language.Name}}</a></td>
+<td>{{range .Tags}}<a href="{{$.Root}}{{.Path}}">{{.Name}}</a> {{end}}</td>
+<td>{{.Description}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+
+{{define "index"}}{{template "head" .}}
+<input type="search" id="search" placeholder="Search by name, description, language, tag or source" autofocus>
+<h2>Languages</h2>
+<p class="links">{{range .Languages}}<a href="{{.Path}}">{{.Name}} ({{.Count}})</a>{{end}}</p>
+{{if .Tags}}<h2>Tags</h2>
+<p class="links">{{range .Tags}}<a href="{{.Path}}">{{.Name}} ({{.Count}})</a>{{end}}</p>{{end}}
+<h2>Snippets</h2>
+{{template "table" .}}
+<script>
+document.getElementById("search").addEventListener("input", function (e) {
+  var words = e.target.value.toLowerCase().split(/\s+/).filter(Boolean);
+  document.querySelectorAll("#snippets tbody tr").forEach(function (row) {
+    var text = row.dataset.search;
+    row.hidden = !words.every(function (w) { return text.includes(w); });
+  });
+});
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "list"}}{{template "head" .}}
+{{template "table" .}}
+</body>
+</html>
+{{end}}
+
+{{define "snippet"}}{{template "head" .}}{{with .Snippet}}
+<p class="meta">
+<a href="{{$.Root}}{{.Language.Path}}">{{.Language.Name}}</a>
+{{range .Tags}} · <a href="{{$.Root}}{{.Path}}">#{{.Name}}</a>{{end}}
+· version {{.Version}} · {{.DateAdded.Format "2006-01-02 15:04"}}
+{{if .Library}} · {{.Library}}{{end}}
+</p>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Source}}<p class="meta">Source: {{.Source}}</p>{{end}}
+{{.Code}}
+{{if .History}}<h2>History</h2>
+{{range .History}}<details>
+<summary>Version {{.Version}} · {{.DateAdded.Format "2006-01-02 15:04"}}{{if .Name}} · {{.Name}}{{end}}</summary>
+{{.Code}}
+</details>
+{{end}}{{end}}
+<p class="meta">{{.Uuid}}</p>
+{{end}}</body>
+</html>
+{{end}}
+`))
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+func (c *CLIOpts) handleTrashOptType(ctx context.Context, db database.DatabaseInteractions) error {
+	switch c.OptType {
+	case OptTypeTrashList:
+		snippets, err := db.GetDeletedSnippets(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to list the trash: %w", err)
+		}
+		if len(snippets) < 1 {
+			fmt.Println("The trash is empty")
+			return nil
+		}
+		displayTrashList(snippets)
+	case OptTypeTrashEmpty:
+		var olderThan time.Duration
+		if age := c.FlagOptions[FlagOptionOlderThan]; age != "" {
+			var err error
+			olderThan, err = common.ParseAge(age)
+			if err != nil {
+				return err
+			}
+		}
+		purged, err := db.EmptyTrash(ctx, olderThan)
+		if err != nil {
+			return fmt.Errorf("unable to empty the trash: %w", err)
+		}
+		fmt.Printf("%d code snippets permanently deleted\n", purged)
+	case OptTypeRestore:
+		id, err := uuid.Parse(c.FlagOptions[FlagOptionUUID])
+		if err != nil {
+			return fmt.Errorf("unable to parse provided UUID")
+		}
+		if err := db.RestoreSnippetByUUID(ctx, id); err != nil {
+			return fmt.Errorf("unable to restore code snippet: %w", err)
+		}
+		fmt.Println("Code snippet restored")
+	}
+	return nil
+}
+
+func displayTrashList(snippets []models.CodeSnippet) {
+	fmt.Printf("%-36s	%-25s	%-10s	%-20s\n", "Uuid", "Name", "Language", "Deleted")
+	for _, s := range snippets {
+		fmt.Printf("%-36s	%-25s	%-10s	%-20s\n",
+			truncate(s.Uuid.String(), 36),
+			truncate(s.Name, 25),
+			truncate(s.Language, 10),
+			s.DeletedAt.Local().Format(time.DateTime),
+		)
+	}
+}
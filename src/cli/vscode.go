@@ -0,0 +1,479 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+// OutputVSCode is the export and import format for VS Code snippet files
+const OutputVSCode = "vscode"
+
+// vscodeExtension is the extension of VS Code snippet files that can hold snippets for any language
+const vscodeExtension = ".code-snippets"
+
+// vscodeSnippet is a single snippet in a VS Code snippets file, where it is keyed by its title
+type vscodeSnippet struct {
+	Scope       string     `json:"scope,omitempty"`
+	Prefix      stringList `json:"prefix"`
+	Body        stringList `json:"body"`
+	Description string     `json:"description,omitempty"`
+}
+
+// stringList is a string or a list of strings, VS Code accepts either for a prefix or a body
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a string or a list of strings")
+	}
+	*l = list
+	return nil
+}
+
+func (l stringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// readVSCodeSnippets reads a VS Code snippets file. A language specific file such as go.json has no scopes,
+// its language is taken from the file name.
+func readVSCodeSnippets(entries *importEntries, data []byte, name string) error {
+	fileLanguage := ""
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".json") {
+		fileLanguage, _ = common.LanguageFromEditorID(strings.TrimSuffix(filepath.Base(name), ext))
+	}
+
+	// the file is decoded a token at a time so the snippets keep the order they were written in
+	dec := json.NewDecoder(bytes.NewReader(stripJSONComments(data)))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("expected a VS Code snippets file to be a json object")
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		title := tok.(string)
+
+		var entry vscodeSnippet
+		if err := dec.Decode(&entry); err != nil {
+			return fmt.Errorf("snippet %q: %w", title, err)
+		}
+
+		snippet := models.CodeSnippet{
+			Name:        title,
+			Code:        vscodeBodyToCode(strings.Join(entry.Body, "\n")),
+			Language:    vscodeScopeLanguage(entry.Scope, fileLanguage),
+			Description: entry.Description,
+		}
+		if len(entry.Prefix) > 0 && entry.Prefix[0] != "" {
+			snippet.Name = entry.Prefix[0]
+			// the title is often the only description a snippet has
+			if snippet.Description == "" && title != snippet.Name {
+				snippet.Description = title
+			}
+		}
+		entries.add(snippet, name, title)
+	}
+	return nil
+}
+
+// vscodeScopeLanguage is the first language in a comma separated scope that chroma knows,
+// the snippet is plain text when it has none
+func vscodeScopeLanguage(scope string, fileLanguage string) string {
+	for _, id := range strings.Split(scope, ",") {
+		if lang, ok := common.LanguageFromEditorID(strings.TrimSpace(id)); ok {
+			return lang
+		}
+	}
+	if fileLanguage != "" {
+		return fileLanguage
+	}
+	return "plaintext"
+}
+
+// vscodeVariables are the variables VS Code fills in itself, any other $NAME in a body is kept as written
+var vscodeVariables = []string{
+	"TM_SELECTED_TEXT", "TM_CURRENT_LINE", "TM_CURRENT_WORD", "TM_LINE_INDEX", "TM_LINE_NUMBER", "TM_FILENAME",
+	"TM_FILENAME_BASE", "TM_DIRECTORY", "TM_FILEPATH", "RELATIVE_FILEPATH", "CLIPBOARD", "WORKSPACE_NAME",
+	"WORKSPACE_FOLDER", "CURSOR_INDEX", "CURSOR_NUMBER", "CURRENT_YEAR", "CURRENT_YEAR_SHORT", "CURRENT_MONTH",
+	"CURRENT_MONTH_NAME", "CURRENT_MONTH_NAME_SHORT", "CURRENT_DATE", "CURRENT_DAY_NAME", "CURRENT_DAY_NAME_SHORT",
+	"CURRENT_HOUR", "CURRENT_MINUTE", "CURRENT_SECOND", "CURRENT_SECONDS_UNIX", "CURRENT_TIMEZONE_OFFSET", "RANDOM",
+	"RANDOM_HEX", "UUID", "BLOCK_COMMENT_START", "BLOCK_COMMENT_END", "LINE_COMMENT",
+}
+
+// vscodeBodyToCode turns snippet syntax into the code it inserts when every placeholder is left as it is:
+// tabstops are removed, placeholders and variables become their default text and choices their first option
+func vscodeBodyToCode(body string) string {
+	code, _ := parseSnippetText(body, 0, false)
+	return code
+}
+
+// parseSnippetText converts body from i, stopping after the closing brace when nested inside a placeholder
+func parseSnippetText(body string, i int, nested bool) (string, int) {
+	var b strings.Builder
+	for i < len(body) {
+		c := body[i]
+		switch {
+		case c == '\\' && i+1 < len(body) && strings.IndexByte(`$}\`, body[i+1]) >= 0:
+			b.WriteByte(body[i+1])
+			i += 2
+		case c == '}' && nested:
+			return b.String(), i + 1
+		case c == '$':
+			text, next := parseSnippetDollar(body, i)
+			b.WriteString(text)
+			i = next
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), i
+}
+
+// parseSnippetDollar converts the tabstop, placeholder, choice or variable starting at body[i], which is a $
+func parseSnippetDollar(body string, i int) (string, int) {
+	start := i
+	i++
+	braced := i < len(body) && body[i] == '{'
+	if braced {
+		i++
+	}
+
+	// a tabstop is a number, a variable a name that does not start with a digit
+	tabstop := i < len(body) && isDigit(body[i])
+	nameEnd := i
+	for nameEnd < len(body) && (isDigit(body[nameEnd]) || (!tabstop && isNameByte(body[nameEnd]))) {
+		nameEnd++
+	}
+	name := body[i:nameEnd]
+	if name == "" {
+		return "$", start + 1
+	}
+	known := tabstop || slices.Contains(vscodeVariables, name)
+	i = nameEnd
+
+	if !braced {
+		if known {
+			return "", i
+		}
+		return body[start:i], i
+	}
+	if i >= len(body) {
+		return body[start:], len(body)
+	}
+
+	switch body[i] {
+	case '}':
+		if known {
+			return "", i + 1
+		}
+		return body[start : i+1], i + 1
+	case ':':
+		return parseSnippetText(body, i+1, true)
+	case '|':
+		// choices are separated by commas, and a comma, pipe or backslash in a choice is escaped
+		var first strings.Builder
+		inFirst := true
+		for j := i + 1; j < len(body); j++ {
+			c := body[j]
+			switch {
+			case c == '\\' && j+1 < len(body) && strings.IndexByte(`$}\,|`, body[j+1]) >= 0:
+				j++
+				c = body[j]
+			case c == '|' && j+1 < len(body) && body[j+1] == '}':
+				return first.String(), j + 2
+			case c == ',':
+				inFirst = false
+				continue
+			}
+			if inFirst {
+				first.WriteByte(c)
+			}
+		}
+		return body[start:], len(body)
+	case '/':
+		// transforms rewrite text that is not part of a plain snippet, skip to the closing brace
+		// past any ${1:/upcase} in the format
+		depth := 0
+		for j := i; j < len(body); j++ {
+			switch {
+			case body[j] == '\\':
+				j++
+			case body[j] == '{':
+				depth++
+			case body[j] == '}' && depth > 0:
+				depth--
+			case body[j] == '}':
+				return "", j + 1
+			}
+		}
+		return "", len(body)
+	}
+	return body[start:i], i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// stripJSONComments removes the comments and trailing commas VS Code allows in its json files
+func stripJSONComments(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == ',':
+			// a comma with only whitespace or comments before the closing bracket is dropped
+			if j := skipJSONSpace(data, i+1); j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// skipJSONSpace is the index of the first byte from i that is not whitespace or part of a comment
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case strings.IndexByte(" \t\r\n", data[i]) >= 0:
+			i++
+		case bytes.HasPrefix(data[i:], []byte("//")):
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				return len(data)
+			}
+			i += end
+		case bytes.HasPrefix(data[i:], []byte("/*")):
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return len(data)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// vscodeFile is the snippets written to one VS Code snippets file, in the order they were added
+type vscodeFile struct {
+	titles   []string
+	snippets map[string]vscodeSnippet
+}
+
+func (f *vscodeFile) add(snippet models.CodeSnippet, withScope bool) {
+	if f.snippets == nil {
+		f.snippets = map[string]vscodeSnippet{}
+	}
+
+	// titles are the keys of the file so must be unique
+	title := snippet.Name
+	if title == "" {
+		title = snippet.Uuid.String()
+	}
+	for n := 2; ; n++ {
+		if _, exists := f.snippets[title]; !exists {
+			break
+		}
+		title = fmt.Sprintf("%s (%d)", snippet.Name, n)
+	}
+
+	entry := vscodeSnippet{
+		Prefix:      stringList{snippet.Name},
+		Body:        strings.Split(codeToVSCodeBody(snippet.Code, snippet.Language), "\n"),
+		Description: snippet.Description,
+	}
+	if snippet.Name == "" {
+		entry.Prefix = stringList{title}
+	}
+	if withScope {
+		entry.Scope = common.EditorLanguageID(snippet.Language)
+	}
+	f.titles = append(f.titles, title)
+	f.snippets[title] = entry
+}
+
+// write encodes the file with the snippets in the order they were added, which a map would lose
+func (f *vscodeFile) write(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for i, title := range f.titles {
+		key, err := json.Marshal(title)
+		if err != nil {
+			return err
+		}
+		value, err := json.MarshalIndent(f.snippets[title], "  ", "  ")
+		if err != nil {
+			return err
+		}
+		b.WriteString("  ")
+		b.Write(key)
+		b.WriteString(": ")
+		b.Write(value)
+		if i < len(f.titles)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// shellLanguages are the languages whose snippets are command lines that may use cheat sheet placeholders
+var shellLanguages = []string{"bash", "fish", "powershell", "tcsh"}
+
+// shellPlaceholder matches the placeholders used by cheat sheets, <name>, <name=default> and {{name}}
+var shellPlaceholder = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_-]*)(?:=([^<>\n]*))?>|\{\{([^{}\n]+)\}\}`)
+
+var snippetTextEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`)
+var snippetPlaceholderEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`)
+
+// codeToVSCodeBody escapes code so VS Code inserts it as written. In shell snippets cheat sheet placeholders
+// become tabstops, with a placeholder used more than once sharing a tabstop so it is only typed once.
+func codeToVSCodeBody(code string, language string) string {
+	if !slices.Contains(shellLanguages, language) {
+		return snippetTextEscaper.Replace(code)
+	}
+
+	var b strings.Builder
+	tabstops := map[string]int{}
+	last := 0
+	for _, m := range shellPlaceholder.FindAllStringSubmatchIndex(code, -1) {
+		b.WriteString(snippetTextEscaper.Replace(code[last:m[0]]))
+		last = m[1]
+
+		name, text := "", ""
+		if m[2] >= 0 {
+			name = code[m[2]:m[3]]
+			text = name
+			if m[4] >= 0 {
+				text = code[m[4]:m[5]]
+			}
+		} else {
+			name = strings.TrimSpace(code[m[6]:m[7]])
+			text = name
+		}
+
+		n, ok := tabstops[name]
+		if !ok {
+			n = len(tabstops) + 1
+			tabstops[name] = n
+		}
+		b.WriteString("${" + strconv.Itoa(n) + ":" + snippetPlaceholderEscaper.Replace(text) + "}")
+	}
+	b.WriteString(snippetTextEscaper.Replace(code[last:]))
+	return b.String()
+}
+
+// writeVSCodeExport writes snippets as VS Code snippets. A path ending in .code-snippets or .json, or stdout,
+// gets a single file with a scope on each snippet. Any other path is a directory given a language snippets file
+// per language, such as go.json, which VS Code scopes by its name so the snippets in it have none.
+func writeVSCodeExport(path string, snippets []models.CodeSnippet) error {
+	if path == "" || path == "-" || strings.EqualFold(filepath.Ext(path), vscodeExtension) || strings.EqualFold(filepath.Ext(path), ".json") {
+		var file vscodeFile
+		for _, snippet := range snippets {
+			file.add(snippet, true)
+		}
+		if path == "" || path == "-" {
+			return file.write(os.Stdout)
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer f.Close()
+		if err := file.write(f); err != nil {
+			return fmt.Errorf("unable to write export: %w", err)
+		}
+		fmt.Printf("Exported %d code snippets to %s\n", len(snippets), path)
+		return nil
+	}
+
+	var ids []string
+	files := map[string]*vscodeFile{}
+	for _, snippet := range snippets {
+		id := common.EditorLanguageID(snippet.Language)
+		if files[id] == nil {
+			ids = append(ids, id)
+			files[id] = &vscodeFile{}
+		}
+		files[id].add(snippet, false)
+	}
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("unable to create export directory: %w", err)
+	}
+	for _, id := range ids {
+		f, err := os.Create(filepath.Join(path, id+".json"))
+		if err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		err = files[id].write(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to write export: %w", err)
+		}
+	}
+	fmt.Printf("Exported %d code snippets to %d files in %s\n", len(snippets), len(ids), path)
+	return nil
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+func TestVSCodeBodyToCode(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"tabstop", "fmt.Println($1)$0", "fmt.Println()"},
+		{"braced tabstop", "fmt.Println(${1})", "fmt.Println()"},
+		{"placeholder", "for ${1:i} := range ${2:items} {", "for i := range items {"},
+		{"nested placeholder", "${1:outer ${2:inner} text}", "outer inner text"},
+		{"unclosed placeholder", "${1:text", "text"},
+		{"choice", "git ${1|push,pull|}", "git push"},
+		{"choice with escapes", `${1|a\,b,c|}`, "a,b"},
+		{"known variable", "// ${TM_FILENAME} $CURRENT_YEAR", "//  "},
+		{"variable default", "${TM_SELECTED_TEXT:text}", "text"},
+		{"unknown variable", "echo $HOME ${PATH}", "echo $HOME ${PATH}"},
+		{"transform", "${TM_FILENAME/(.*)\\.go/$1/}_test.go", "_test.go"},
+		{"transform with nested format", "${TM_FILENAME/(.*)/${1:/upcase}/}.go", ".go"},
+		{"escaped dollar", `echo \$HOME \$1`, "echo $HOME $1"},
+		{"escaped brace in placeholder", `${1:a\}b}`, "a}b"},
+		{"escaped backslash", `a\\b`, `a\b`},
+		{"lone dollar", "cost $ and ${", "cost $ and ${"},
+		{"unescaped closing brace", "func() {}", "func() {}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vscodeBodyToCode(tt.body); got != tt.want {
+				t.Errorf("vscodeBodyToCode(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"line comment", "{\n  // a comment\n  \"a\": 1 // trailing\n}", `{"a": 1}`},
+		{"block comment", `{/* a */"a": /* b */ 1}`, `{"a": 1}`},
+		{"trailing comma in object", `{"a": 1, "b": 2,}`, `{"a": 1, "b": 2}`},
+		{"trailing comma in array", `{"a": [1, 2, ]}`, `{"a": [1, 2]}`},
+		{"trailing comma before comment", "{\"a\": 1, // last\n}", `{"a": 1}`},
+		{"trailing comma before block comment", `{"a": [1, /* end */ ]}`, `{"a": [1]}`},
+		{"comment markers in strings", `{"a": "// not a comment", "b": "/* nor this */"}`, `{"a": "// not a comment", "b": "/* nor this */"}`},
+		{"escaped quote in string", `{"a": "say \"hi\" // still a string",}`, `{"a": "say \"hi\" // still a string"}`},
+		{"comma in string", `{"a": ",}"}`, `{"a": ",}"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, want any
+			if err := json.Unmarshal(stripJSONComments([]byte(tt.data)), &got); err != nil {
+				t.Fatalf("stripped %q is not valid json: %v", tt.data, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("stripJSONComments(%q) decodes to %v, want %v", tt.data, got, want)
+			}
+		})
+	}
+}
+
+func TestCodeToVSCodeBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{"plain code", "x := 1", "go", "x := 1"},
+		{"dollar and backslash", `fmt.Printf("$%d\n", n)`, "go", `fmt.Printf("\$%d\\n", n)`},
+		{"placeholders outside shell", "ls <dir> {{name}}", "go", "ls <dir> {{name}}"},
+		{"shell placeholder", "ls <dir>", "bash", "ls ${1:dir}"},
+		{"shell placeholder default", "ls <dir=.>", "bash", "ls ${1:.}"},
+		{"shell mustache placeholder", "echo {{ name }}", "fish", "echo ${1:name}"},
+		{"repeated placeholder", "cp <file> <file>.bak <dest>", "bash", "cp ${1:file} ${1:file}.bak ${2:dest}"},
+		{"placeholder default escaped", "echo <msg=a}$b>", "bash", `echo ${1:a\}\$b}`},
+		{"shell variables", `echo "$HOME" ${PATH}`, "bash", `echo "\$HOME" \${PATH}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codeToVSCodeBody(tt.code, tt.language); got != tt.want {
+				t.Errorf("codeToVSCodeBody(%q, %q) = %q, want %q", tt.code, tt.language, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVSCodeRoundTrip exports snippets as a single file and as a directory of language files and reads them back
+func TestVSCodeRoundTrip(t *testing.T) {
+	snippets := []models.CodeSnippet{
+		{Name: "printf", Code: "fmt.Printf(\"$%d\\n\", n)\nif ok {\n\treturn\n}", Language: "go", Description: "print money"},
+		{Name: "home", Code: `echo "${HOME:-/root}" \$1`, Language: "bash"},
+		{Name: "copy", Code: "cp <file> <dest=/tmp>", Language: "bash"},
+	}
+	// cheat sheet placeholders become tabstops, which read back as their default text
+	want := []models.CodeSnippet{
+		snippets[0],
+		snippets[1],
+		{Name: "copy", Code: "cp file /tmp", Language: "bash"},
+	}
+
+	var file vscodeFile
+	for _, snippet := range snippets {
+		file.add(snippet, true)
+	}
+	var b bytes.Buffer
+	if err := file.write(&b); err != nil {
+		t.Fatal(err)
+	}
+	entries := importEntries{format: OutputVSCode}
+	if err := readVSCodeSnippets(&entries, b.Bytes(), "csnip.code-snippets"); err != nil {
+		t.Fatal(err)
+	}
+	checkRoundTrip(t, entries.snippets, want)
+
+	dir := t.TempDir()
+	if err := writeVSCodeExport(dir, snippets); err != nil {
+		t.Fatal(err)
+	}
+	entries = importEntries{format: OutputVSCode}
+	for _, name := range []string{"go.json", "shellscript.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(`"scope"`)) {
+			t.Errorf("%s has scopes, language files should not", name)
+		}
+		if err := readVSCodeSnippets(&entries, data, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkRoundTrip(t, entries.snippets, want)
+}
+
+func checkRoundTrip(t *testing.T, got []models.CodeSnippet, want []models.CodeSnippet) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read back %d snippets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].Code != want[i].Code || got[i].Language != want[i].Language || got[i].Description != want[i].Description {
+			t.Errorf("snippet %d read back as %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -2,8 +2,14 @@ package common
 
 import (
 	"fmt"
-	"github.com/alecthomas/chroma/v2/lexers"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/lexers"
 )
 
 func ReadFromFile(s string) (string, error) {
@@ -15,16 +21,160 @@ func ReadFromFile(s string) (string, error) {
 	return string(data), nil
 }
 
+// ValidateLanguage reports whether lang is the name or alias of a language known to chroma, ignoring case
 func ValidateLanguage(lang string) bool {
-	// normalise string so that it always titles
-	validLangs := lexers.Names(true)
+	_, ok := CanonicalLanguage(lang)
+	return ok
+}
+
+// CanonicalLanguage returns the lower case chroma name for a language name or alias, so go, Go and golang are all stored as go
+func CanonicalLanguage(lang string) (string, bool) {
+	lang = strings.TrimSpace(lang)
+	if lang == "" {
+		return "", false
+	}
+	// lexers.Get falls back to matching filenames, only accept real names and aliases
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return "", false
+	}
+	config := lexer.Config()
+	if !strings.EqualFold(config.Name, lang) && !slices.ContainsFunc(config.Aliases, func(alias string) bool {
+		return strings.EqualFold(alias, lang)
+	}) {
+		return "", false
+	}
+	return strings.ToLower(config.Name), true
+}
 
-	for _, item := range validLangs {
-		if lang == item {
-			return true
+// DetectLanguage guesses the language of code, from the extension of filename when there is one
+// and otherwise from the code itself. It returns false if no language could be detected.
+func DetectLanguage(filename string, code string) (string, bool) {
+	if filename != "" {
+		if lexer := lexers.Match(filepath.Base(filename)); lexer != nil {
+			return strings.ToLower(lexer.Config().Name), true
 		}
 	}
-	return false
+	if lexer := lexers.Analyse(code); lexer != nil {
+		return strings.ToLower(lexer.Config().Name), true
+	}
+	return "", false
+}
+
+// editorLanguages maps the language identifiers used by VS Code and language server clients that are not
+// chroma names or aliases to the chroma name they are stored as
+var editorLanguages = map[string]string{
+	"shellscript":     "bash",
+	"javascriptreact": "react",
+	"typescriptreact": "typescript",
+	"jsonc":           "json",
+	"objective-cpp":   "objective-c",
+	"vb":              "vb.net",
+	"razor":           "html",
+	"less":            "css",
+	"git-commit":      "plaintext",
+	"ignore":          "plaintext",
+}
+
+// editorLanguageIDs are the editor identifiers for the chroma names that differ from them
+var editorLanguageIDs = map[string]string{
+	"bash":      "shellscript",
+	"c#":        "csharp",
+	"c++":       "cpp",
+	"docker":    "dockerfile",
+	"batchfile": "bat",
+	"vb.net":    "vb",
+	"react":     "javascriptreact",
+	"tex":       "latex",
+}
+
+// LanguageFromEditorID returns the language snippets are stored as for an editor's language identifier, such as
+// shellscript or csharp
+func LanguageFromEditorID(id string) (string, bool) {
+	if lang, ok := editorLanguages[strings.ToLower(id)]; ok {
+		return lang, true
+	}
+	return CanonicalLanguage(id)
+}
+
+// EditorLanguageID returns the editor's language identifier for a stored language, the reverse of LanguageFromEditorID
+func EditorLanguageID(lang string) string {
+	if id, ok := editorLanguageIDs[lang]; ok {
+		return id
+	}
+	return lang
+}
+
+// SuggestLanguages returns up to three known languages with names or aliases closest to lang, best match first
+func SuggestLanguages(lang string) []string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	maxDistance := max(2, len(lang)/3)
+
+	best := map[string]int{}
+	for _, name := range lexers.Names(true) {
+		if distance := levenshtein(lang, strings.ToLower(name)); distance <= maxDistance {
+			canonical, ok := CanonicalLanguage(name)
+			if !ok {
+				continue
+			}
+			if current, seen := best[canonical]; !seen || distance < current {
+				best[canonical] = distance
+			}
+		}
+	}
+
+	suggestions := make([]string, 0, len(best))
+	for name := range best {
+		suggestions = append(suggestions, name)
+	}
+	slices.SortFunc(suggestions, func(a, b string) int {
+		if best[a] != best[b] {
+			return best[a] - best[b]
+		}
+		return strings.Compare(a, b)
+	})
+	return suggestions[:min(len(suggestions), 3)]
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// ParseTags splits a comma separated list of tags and normalises them
+func ParseTags(s string) []string {
+	return NormaliseTags(strings.Split(s, ","))
+}
+
+// NormaliseTags trims and lower cases tags, removing empty and duplicate entries. The result is sorted.
+func NormaliseTags(tags []string) []string {
+	var normalised []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(normalised, tag) {
+			continue
+		}
+		normalised = append(normalised, tag)
+	}
+	slices.Sort(normalised)
+	return normalised
 }
 
 func ListValidLanguages() []string {
@@ -47,3 +197,41 @@ var helloWorldMap = map[string]string{
 func GetHelloWorldExamples() map[string]string {
 	return helloWorldMap
 }
+
+// ParseAge parses a duration such as 30d or 2w as well as anything time.ParseDuration accepts
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q, expected something like 30d", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q, expected something like 30d", s)
+	}
+	return d, nil
+}
+
+// ParseDateOrAge parses a date such as 2006-01-02 in local time, or an age such as 30d counted back from now
+func ParseDateOrAge(s string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	age, err := ParseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected a date like 2006-01-02 or an age like 30d", s)
+	}
+	return time.Now().Add(-age), nil
+}
@@ -6,15 +6,54 @@ import (
 )
 
 type CodeSnippet struct {
-	ID           int64
-	Uuid         uuid.UUID
-	Name         string
-	Code         string
-	Language     string
-	Tags         string
-	Description  string
-	Source       string
-	DateAdded    time.Time
-	Version      int64
-	SupersededBy int64
+	ID           int64      `json:"id" yaml:"id"`
+	Uuid         uuid.UUID  `json:"uuid" yaml:"uuid"`
+	Name         string     `json:"name" yaml:"name"`
+	Code         string     `json:"code" yaml:"code"`
+	Language     string     `json:"language" yaml:"language"`
+	Tags         []string   `json:"tags" yaml:"tags"`
+	Description  string     `json:"description" yaml:"description"`
+	Source       string     `json:"source" yaml:"source"`
+	DateAdded    time.Time  `json:"date_added" yaml:"date_added"`
+	Version      int64      `json:"version" yaml:"version"`
+	SupersededBy int64      `json:"superseded_by,omitempty" yaml:"superseded_by,omitempty"`
+	Groups       []string   `json:"groups,omitempty" yaml:"groups,omitempty"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" yaml:"deleted_at,omitempty"` // nil unless the snippet is in the trash
+	Library      string     `json:"library,omitempty" yaml:"library,omitempty"`       // set when reading from more than one library
+}
+
+// ExportFormat is the version of the document written by csnip export, raised when older versions could not read it
+const ExportFormat = 1
+
+// Export is the document written by csnip export and read by csnip import.
+// Every version of a snippet shares its uuid and they are listed oldest first.
+type Export struct {
+	Format   int           `json:"csnip_export" yaml:"csnip_export"`
+	Exported time.Time     `json:"exported" yaml:"exported"`
+	Snippets []CodeSnippet `json:"snippets" yaml:"snippets"`
+}
+
+// SearchResult is a snippet matched by a full text search.
+// Rank orders results with the best match lowest, Fragments hold the highlighted text that matched.
+type SearchResult struct {
+	CodeSnippet `yaml:",inline"`
+	Rank        float64  `json:"rank" yaml:"rank"`
+	Fragments   []string `json:"fragments" yaml:"fragments"`
+}
+
+// TagCount is a tag along with the number of snippets using it
+type TagCount struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int64  `json:"count" yaml:"count"`
+}
+
+// Group is a named, ordered collection of snippets
+type Group struct {
+	ID          int64       `json:"id" yaml:"id"`
+	Name        string      `json:"name" yaml:"name"`
+	Description string      `json:"description" yaml:"description"`
+	Snippets    []uuid.UUID `json:"snippets" yaml:"snippets"`
+	DateAdded   time.Time   `json:"date_added" yaml:"date_added"`
+	DateUpdated time.Time   `json:"date_updated" yaml:"date_updated"`
+	Library     string      `json:"library,omitempty" yaml:"library,omitempty"` // set when reading from more than one library
 }
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Config holds the user configurable settings for csnip.
+type Config struct {
+	Database  string `json:"database"`
+	Theme     string `json:"theme"`
+	Formatter string `json:"formatter"`
+	Clipboard bool   `json:"clipboard"`
+	PageSize  int64  `json:"page_size"`
+	Socket    string `json:"socket"`
+	Listen    string `json:"listen"`
+	// Token is required as a bearer token by the daemon's tcp address when set
+	Token string `json:"token,omitempty"`
+
+	// Libraries are read after the personal database in the order listed
+	Libraries    []Library `json:"libraries,omitempty"`
+	WriteLibrary string    `json:"write_library,omitempty"`
+}
+
+// Library is a snippet database shared alongside the personal database, such as one checked into a team repository
+type Library struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// PersonalLibrary is the name of the library stored at Config.Database
+const PersonalLibrary = "personal"
+
+// custom errors returned when reading or changing config values
+var (
+	ErrUnknownKey      = errors.New("unknown config key")
+	ErrLibraryExists   = errors.New("a library with the given name already exists")
+	ErrLibraryNotFound = errors.New("no library found with the given name")
+)
+
+// keys accepted by Get and Set, in the order they are listed
+var keys = []string{"database", "theme", "formatter", "clipboard", "page_size", "socket", "listen", "token", "write_library"}
+
+// Default returns the config used when no config file exists
+func Default() Config {
+	return Config{
+		Database:  defaultDatabasePath(),
+		Theme:     DefaultTheme,
+		Formatter: DefaultFormatter,
+		Clipboard: DefaultClipboard,
+		PageSize:  DefaultPageSize,
+		Socket:    defaultSocketPath(),
+	}
+}
+
+// Path returns the location of the config file, CSNIP_CONFIG takes priority over the XDG config directory
+func Path() (string, error) {
+	if p := os.Getenv(EnvConfigPath); p != "" {
+		return expandHome(p), nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "csnip", "config.json"), nil
+}
+
+// Load reads the config file and applies any environment overrides.
+// A missing config file is not an error, the defaults are used instead.
+func Load() (Config, error) {
+	path, err := Path()
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	if db := os.Getenv(EnvDatabasePath); db != "" {
+		cfg.Database = expandHome(db)
+	}
+	return cfg, nil
+}
+
+// LoadFile reads the config at path without applying environment overrides.
+// Values missing from the file keep their defaults.
+func LoadFile(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+
+	cfg.Database = expandHome(cfg.Database)
+	cfg.Socket = expandHome(cfg.Socket)
+	for i := range cfg.Libraries {
+		cfg.Libraries[i].Path = expandHome(cfg.Libraries[i].Path)
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config to path, creating the directory if needed
+func (c Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode config: %w", err)
+	}
+
+	// the token lets anyone who reads it use the daemon, so the file is never readable by others while it holds one.
+	// It is written to a temporary file, which starts out 0600, and renamed over the config.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if c.Token == "" {
+		if err := tmp.Chmod(0o644); err != nil {
+			tmp.Close()
+			return fmt.Errorf("unable to set config file permissions: %w", err)
+		}
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	return nil
+}
+
+// Keys returns the config keys that can be used with Get and Set
+func Keys() []string {
+	return slices.Clone(keys)
+}
+
+// Get returns the value of key formatted as a string
+func (c Config) Get(key string) (string, error) {
+	switch strings.ToLower(key) {
+	case "database":
+		return c.Database, nil
+	case "theme":
+		return c.Theme, nil
+	case "formatter":
+		return c.Formatter, nil
+	case "clipboard":
+		return strconv.FormatBool(c.Clipboard), nil
+	case "page_size":
+		return strconv.FormatInt(c.PageSize, 10), nil
+	case "socket":
+		return c.Socket, nil
+	case "listen":
+		return c.Listen, nil
+	case "token":
+		return c.Token, nil
+	case "write_library":
+		if c.WriteLibrary == "" {
+			return PersonalLibrary, nil
+		}
+		return c.WriteLibrary, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownKey, key)
+}
+
+// Set parses value and stores it against key, returning an error if the value is not valid for the key
+func (c *Config) Set(key string, value string) error {
+	updated := *c
+
+	switch strings.ToLower(key) {
+	case "database":
+		updated.Database = expandHome(value)
+	case "theme":
+		updated.Theme = value
+	case "formatter":
+		updated.Formatter = value
+	case "clipboard":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("clipboard must be true or false")
+		}
+		updated.Clipboard = b
+	case "page_size":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("page_size must be a number")
+		}
+		updated.PageSize = n
+	case "socket":
+		updated.Socket = expandHome(value)
+	case "listen":
+		updated.Listen = value
+	case "token":
+		updated.Token = value
+	case "write_library":
+		updated.WriteLibrary = value
+		if strings.EqualFold(value, PersonalLibrary) {
+			updated.WriteLibrary = ""
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+
+	if err := updated.validate(); err != nil {
+		return err
+	}
+	*c = updated
+	return nil
+}
+
+func (c Config) validate() error {
+	if c.Database == "" {
+		return fmt.Errorf("database must not be empty")
+	}
+	if !slices.Contains(styles.Names(), c.Theme) {
+		return fmt.Errorf("unknown theme %q, expected one of: %s", c.Theme, strings.Join(styles.Names(), ","))
+	}
+	if !slices.Contains(formatters.Names(), c.Formatter) {
+		return fmt.Errorf("unknown formatter %q, expected one of: %s", c.Formatter, strings.Join(formatters.Names(), ","))
+	}
+	if c.PageSize < 1 {
+		return fmt.Errorf("page_size must be greater than 0")
+	}
+	if c.Socket == "" {
+		return fmt.Errorf("socket must not be empty")
+	}
+	if err := c.validateLibraries(); err != nil {
+		return err
+	}
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			return fmt.Errorf("listen must be an address such as 127.0.0.1:7420: %w", err)
+		}
+	}
+	return nil
+}
+
+// AddLibrary adds a library read after the personal database and any libraries already added
+func (c *Config) AddLibrary(name string, path string) error {
+	updated := *c
+	updated.Libraries = append(slices.Clone(c.Libraries), Library{Name: name, Path: expandHome(path)})
+	if err := updated.validateLibraries(); err != nil {
+		return err
+	}
+	*c = updated
+	return nil
+}
+
+// RemoveLibrary removes the library with name, writes go back to the personal database if it was the write library
+func (c *Config) RemoveLibrary(name string) error {
+	i := slices.IndexFunc(c.Libraries, func(l Library) bool {
+		return strings.EqualFold(l.Name, name)
+	})
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrLibraryNotFound, name)
+	}
+	c.Libraries = slices.Delete(slices.Clone(c.Libraries), i, i+1)
+	if strings.EqualFold(c.WriteLibrary, name) {
+		c.WriteLibrary = ""
+	}
+	return nil
+}
+
+func (c Config) validateLibraries() error {
+	names := []string{PersonalLibrary}
+	for _, l := range c.Libraries {
+		if strings.TrimSpace(l.Name) == "" {
+			return fmt.Errorf("library names must not be empty")
+		}
+		if l.Path == "" {
+			return fmt.Errorf("library %s must have a path", l.Name)
+		}
+		if slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, l.Name) }) {
+			return fmt.Errorf("%w: %s", ErrLibraryExists, l.Name)
+		}
+		names = append(names, l.Name)
+	}
+	if c.WriteLibrary != "" && !slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, c.WriteLibrary) }) {
+		return fmt.Errorf("write_library %q is not a library, expected one of: %s", c.WriteLibrary, strings.Join(names, ","))
+	}
+	return nil
+}
+
+// defaultDatabasePath places the database in the XDG data directory, falling back to the config directory
+func defaultDatabasePath() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "csnip", "csnip.db")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "csnip", "csnip.db")
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "csnip", "csnip.db")
+	}
+	return "csnip.db"
+}
+
+// expandHome replaces a leading ~ with the users home directory
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
+
+// defaultSocketPath places the daemon's socket in the XDG runtime directory, falling back to beside the default database
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "csnip", "csnip.sock")
+	}
+	return filepath.Join(filepath.Dir(defaultDatabasePath()), "csnip.sock")
+}
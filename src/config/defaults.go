@@ -0,0 +1,14 @@
+package config
+
+const (
+	DefaultTheme     = "monokai"
+	DefaultFormatter = "terminal"
+	DefaultClipboard = true
+	DefaultPageSize  = 100
+)
+
+// environment variables that override the location of the config file and database
+const (
+	EnvConfigPath   = "CSNIP_CONFIG"
+	EnvDatabasePath = "CSNIP_DB"
+)
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// requireAccess guards the api against other programs on the machine and the browser. The unix socket is only
+// reachable by the user, so the checks apply to requests on the tcp address: the Host must be the listen address
+// or localhost so a DNS rebound page cannot reach the api, and the token must be sent when one is configured.
+// Browsers add an Origin header to cross origin requests, no client of the api is a web page so any Origin is refused.
+func requireAccess(opts Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("requests from web pages are not allowed"))
+			return
+		}
+
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !allowedHost(r.Host, opts.Listen) {
+			writeError(w, http.StatusForbidden, errors.New("host is not the address the daemon listens on"))
+			return
+		}
+		if opts.Token != "" {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(token), []byte(opts.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("a valid token is required"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, the Host of a request, names the listen address or localhost.
+// When listening on every interface the machine may be reached on any of its addresses, so any ip is allowed,
+// a rebound page always uses a host name.
+func allowedHost(host string, listen string) bool {
+	if strings.EqualFold(host, listen) {
+		return true
+	}
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	name = strings.Trim(name, "[]")
+	if strings.EqualFold(name, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(name)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	listenHost, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	listenIP := net.ParseIP(listenHost)
+	return listenHost == "" || (listenIP != nil && (listenIP.IsUnspecified() || listenIP.Equal(ip)))
+}
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-Har/csnip/database"
+)
+
+// shutdownTimeout is how long in flight requests are given to finish once the daemon is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// custom errors returned when starting the daemon
+var (
+	ErrAlreadyRunning = errors.New("csnip daemon is already running")
+	ErrTokenRequired  = errors.New("a token must be set with csnip config set token before listening on a non loopback address")
+)
+
+// Options controls where the daemon listens
+type Options struct {
+	Socket  string // path of the unix socket, always served
+	Listen  string // optional tcp address such as 127.0.0.1:7420
+	Token   string // required from clients of Listen when set, and before listening on a non loopback address
+	PIDFile string // defaults to csnip.pid beside the socket
+	Logger  *slog.Logger
+}
+
+// Run serves the API until ctx is cancelled, then waits for in flight requests before returning
+func Run(ctx context.Context, db database.DatabaseInteractions, opts Options) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
+	if opts.PIDFile == "" {
+		opts.PIDFile = filepath.Join(filepath.Dir(opts.Socket), "csnip.pid")
+	}
+
+	pidFile, err := acquirePIDFile(opts.PIDFile)
+	if err != nil {
+		return err
+	}
+	defer releasePIDFile(pidFile)
+
+	listeners, err := listen(opts)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(opts.Socket)
+
+	srv := &http.Server{
+		Handler:           logRequests(logger, requireAccess(opts, newServer(db).routes())),
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func() {
+			if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("failed to serve on %s: %w", l.Addr(), err)
+			}
+		}()
+	}
+	logger.Info("daemon started", "pid", os.Getpid(), "socket", opts.Socket, "listen", opts.Listen)
+
+	select {
+	case <-ctx.Done():
+	case err := <-errs:
+		srv.Close()
+		return err
+	}
+
+	logger.Info("daemon stopping")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop daemon: %w", err)
+	}
+	logger.Info("daemon stopped")
+	return nil
+}
+
+// listen opens the unix socket and the tcp address if one was given
+func listen(opts Options) ([]net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(opts.Socket), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create socket directory: %w", err)
+	}
+	// the pid file lock is held, so a socket left behind belongs to a daemon that did not shut down cleanly
+	if err := os.Remove(opts.Socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to remove stale socket: %w", err)
+	}
+
+	unixListener, err := net.Listen("unix", opts.Socket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on %s: %w", opts.Socket, err)
+	}
+	if err := os.Chmod(opts.Socket, 0o600); err != nil {
+		unixListener.Close()
+		return nil, fmt.Errorf("unable to restrict socket permissions: %w", err)
+	}
+	listeners := []net.Listener{unixListener}
+
+	if opts.Listen != "" {
+		if !isLoopback(opts.Listen) && opts.Token == "" {
+			unixListener.Close()
+			return nil, fmt.Errorf("%w: %s", ErrTokenRequired, opts.Listen)
+		}
+		tcpListener, err := net.Listen("tcp", opts.Listen)
+		if err != nil {
+			unixListener.Close()
+			return nil, fmt.Errorf("unable to listen on %s: %w", opts.Listen, err)
+		}
+		listeners = append(listeners, tcpListener)
+	}
+	return listeners, nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// acquirePIDFile locks the pid file and writes the daemon's pid to it.
+// The lock is released by the operating system if the daemon dies, so a stale file never blocks a restart.
+func acquirePIDFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create pid file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open pid file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		defer f.Close()
+		if errors.Is(err, ErrAlreadyRunning) {
+			if data, readErr := os.ReadFile(path); readErr == nil {
+				if pid := strings.TrimSpace(string(data)); pid != "" {
+					return nil, fmt.Errorf("%w with pid %s", ErrAlreadyRunning, pid)
+				}
+			}
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to lock pid file: %w", err)
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to write pid file: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to write pid file: %w", err)
+	}
+	return f, nil
+}
+
+// releasePIDFile removes the pid file while it is still locked so another daemon never sees it half removed
+func releasePIDFile(f *os.File) {
+	os.Remove(f.Name())
+	f.Close()
+}
@@ -0,0 +1,590 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+// maxBodySize limits the size of request bodies, snippets are small
+const maxBodySize = 10 << 20
+
+// server handles api requests against the database
+type server struct {
+	db database.DatabaseInteractions
+}
+
+func newServer(db database.DatabaseInteractions) *server {
+	return &server{db: db}
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", s.health)
+
+	mux.HandleFunc("GET /v1/snippets", s.listSnippets)
+	mux.HandleFunc("POST /v1/snippets", s.addSnippet)
+	mux.HandleFunc("GET /v1/snippets/{uuid}", s.getSnippet)
+	mux.HandleFunc("PATCH /v1/snippets/{uuid}", s.updateSnippet)
+	mux.HandleFunc("DELETE /v1/snippets/{uuid}", s.deleteSnippet)
+	mux.HandleFunc("GET /v1/snippets/{uuid}/history", s.snippetHistory)
+	mux.HandleFunc("POST /v1/snippets/{uuid}/revert", s.revertSnippet)
+	mux.HandleFunc("POST /v1/snippets/{uuid}/restore", s.restoreSnippet)
+	mux.HandleFunc("POST /v1/snippets/{uuid}/use", s.recordSnippetUse)
+
+	mux.HandleFunc("GET /v1/trash", s.listTrash)
+	mux.HandleFunc("DELETE /v1/trash", s.emptyTrash)
+
+	mux.HandleFunc("GET /v1/search", s.search)
+	mux.HandleFunc("GET /v1/tags", s.listTags)
+
+	mux.HandleFunc("GET /v1/groups", s.listGroups)
+	mux.HandleFunc("POST /v1/groups", s.createGroup)
+	mux.HandleFunc("GET /v1/groups/{name}", s.getGroup)
+	mux.HandleFunc("DELETE /v1/groups/{name}", s.deleteGroup)
+	mux.HandleFunc("GET /v1/groups/{name}/snippets", s.groupSnippets)
+	mux.HandleFunc("PUT /v1/groups/{name}/snippets/{uuid}", s.addSnippetToGroup)
+	mux.HandleFunc("DELETE /v1/groups/{name}/snippets/{uuid}", s.removeSnippetFromGroup)
+	return mux
+}
+
+// snippetPage is a page of snippets as returned by GET /v1/snippets
+type snippetPage struct {
+	Snippets   []models.CodeSnippet `json:"snippets"`
+	Total      int64                `json:"total"`
+	Offset     int64                `json:"offset"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *server) listSnippets(w http.ResponseWriter, r *http.Request) {
+	filter, err := snippetFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := s.db.QuerySnippetsPage(r.Context(), filter)
+	if err != nil && !errors.Is(err, database.ErrNoSnippetsFound) {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snippetPage{
+		Snippets:   nonNil(page.Snippets),
+		Total:      page.Total,
+		Offset:     page.Offset,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// snippetFilter reads a query filter from the request's query string
+func snippetFilter(r *http.Request) (database.SnippetFilter, error) {
+	query := r.URL.Query()
+	var filter database.SnippetFilter
+	var err error
+
+	filter.Languages = listParam(query["language"])
+	filter.Tags = listParam(query["tag"])
+	switch query.Get("tag_match") {
+	case "", "any":
+	case "all":
+		filter.TagMatch = database.TagMatchAll
+	default:
+		return filter, fmt.Errorf("tag_match must be any or all")
+	}
+	filter.Source = query.Get("source")
+	filter.Name = query.Get("name")
+	filter.Group = query.Get("group")
+
+	if since := query.Get("since"); since != "" {
+		if filter.ModifiedAfter, err = common.ParseDateOrAge(since); err != nil {
+			return filter, err
+		}
+	}
+	if before := query.Get("before"); before != "" {
+		if filter.ModifiedBefore, err = common.ParseDateOrAge(before); err != nil {
+			return filter, err
+		}
+	}
+
+	if filter.Sort, err = database.ParseSortOrder(query.Get("sort")); err != nil {
+		return filter, err
+	}
+	if filter.Reverse, err = boolParam(query.Get("reverse")); err != nil {
+		return filter, fmt.Errorf("reverse must be true or false")
+	}
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		return filter, fmt.Errorf("limit must be a positive number")
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		return filter, fmt.Errorf("offset must be a positive number")
+	}
+	filter.Cursor = query.Get("cursor")
+	return filter, nil
+}
+
+func (s *server) addSnippet(w http.ResponseWriter, r *http.Request) {
+	var snippet models.CodeSnippet
+	if !readJSON(w, r, &snippet) {
+		return
+	}
+	if strings.TrimSpace(snippet.Code) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("code must not be empty"))
+		return
+	}
+
+	if snippet.Language == "" {
+		lang, ok := common.DetectLanguage("", snippet.Code)
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unable to detect the language of the snippet, please provide one"))
+			return
+		}
+		snippet.Language = lang
+	} else if err := canonicaliseLanguage(&snippet); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	snippet.Tags = common.NormaliseTags(snippet.Tags)
+
+	created, err := s.db.AddNewSnippet(r.Context(), snippet)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *server) getSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	snippet, err := s.db.GetSnippetByUUID(r.Context(), id)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snippet)
+}
+
+// updateSnippet stores a new version of the snippet, fields left empty keep their current value
+func (s *server) updateSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	var snippet models.CodeSnippet
+	if !readJSON(w, r, &snippet) {
+		return
+	}
+	if snippet.Language != "" {
+		if err := canonicaliseLanguage(&snippet); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	snippet.Tags = common.NormaliseTags(snippet.Tags)
+
+	updated, err := s.db.UpdateSnippet(r.Context(), id, snippet)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// deleteSnippet moves the snippet to the trash, or removes it for good with ?purge=true
+func (s *server) deleteSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	purge, err := boolParam(r.URL.Query().Get("purge"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("purge must be true or false"))
+		return
+	}
+
+	if purge {
+		err = s.db.PurgeSnippetByUUID(r.Context(), id)
+	} else {
+		err = s.db.DeleteSnippetByUUID(r.Context(), id)
+	}
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	history, err := s.db.GetSnippetHistoryByUUID(r.Context(), id)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *server) revertSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Version int64 `json:"version"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Version < 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("version must be greater than 0"))
+		return
+	}
+
+	reverted, err := s.db.RevertSnippet(r.Context(), id, body.Version)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reverted)
+}
+
+func (s *server) restoreSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.RestoreSnippetByUUID(r.Context(), id); err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) recordSnippetUse(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.RecordSnippetUse(r.Context(), id); err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) listTrash(w http.ResponseWriter, r *http.Request) {
+	snippets, err := s.db.GetDeletedSnippets(r.Context())
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(snippets))
+}
+
+// emptyTrash purges the trash, or only snippets deleted longer ago than ?older_than=30d
+func (s *server) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	var olderThan time.Duration
+	if age := r.URL.Query().Get("older_than"); age != "" {
+		var err error
+		if olderThan, err = common.ParseAge(age); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	purged, err := s.db.EmptyTrash(r.Context(), olderThan)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+func (s *server) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := query.Get("q")
+	if strings.TrimSpace(q) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("a search query must be provided with q"))
+		return
+	}
+
+	opts := database.SearchOptions{
+		Language:       query.Get("language"),
+		HighlightStart: query.Get("highlight_start"),
+		HighlightEnd:   query.Get("highlight_end"),
+	}
+	var err error
+	if opts.Limit, err = intParam(query.Get("limit")); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive number"))
+		return
+	}
+
+	results, err := s.db.Search(r.Context(), q, opts)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(results))
+}
+
+func (s *server) listTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.db.ListTags(r.Context())
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(tags))
+}
+
+func (s *server) listGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.db.GetGroups(r.Context())
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(groups))
+}
+
+func (s *server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("name must not be empty"))
+		return
+	}
+
+	group, err := s.db.CreateGroup(r.Context(), strings.TrimSpace(body.Name), body.Description)
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, group)
+}
+
+func (s *server) getGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := s.db.GetGroupByName(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeleteGroup(r.Context(), r.PathValue("name")); err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) groupSnippets(w http.ResponseWriter, r *http.Request) {
+	snippets, err := s.db.GetSnippetsByGroup(r.Context(), r.PathValue("name"))
+	if err != nil && !errors.Is(err, database.ErrNoSnippetsFound) {
+		writeDatabaseError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(snippets))
+}
+
+func (s *server) addSnippetToGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.AddSnippetToGroup(r.Context(), r.PathValue("name"), id); err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) removeSnippetFromGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := uuidParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.RemoveSnippetFromGroup(r.Context(), r.PathValue("name"), id); err != nil {
+		writeDatabaseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canonicaliseLanguage replaces the snippet's language with its canonical name, suggesting similar languages when it is unknown
+func canonicaliseLanguage(snippet *models.CodeSnippet) error {
+	lang, ok := common.CanonicalLanguage(snippet.Language)
+	if ok {
+		snippet.Language = lang
+		return nil
+	}
+	if suggestions := common.SuggestLanguages(snippet.Language); len(suggestions) > 0 {
+		return fmt.Errorf("unknown language %q, did you mean %s?", snippet.Language, strings.Join(suggestions, ", "))
+	}
+	return fmt.Errorf("unknown language %q", snippet.Language)
+}
+
+// uuidParam parses the uuid in the request path, writing a bad request response if it is not valid
+func uuidParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unable to parse provided UUID"))
+		return id, false
+	}
+	return id, true
+}
+
+// listParam splits repeated and comma separated query values into a single list
+func listParam(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func boolParam(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+func intParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// nonNil returns an empty list rather than nil so empty results are encoded as [] instead of null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// readJSON decodes the request body into v, writing a bad request response if it cannot.
+// The body must be sent as application/json, which a web page cannot do without the browser asking first.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("request body must be sent with Content-Type: application/json"))
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeDatabaseError maps the database package's errors to http status codes
+func writeDatabaseError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, database.ErrNoSnippetsFound),
+		errors.Is(err, database.ErrVersionNotFound),
+		errors.Is(err, database.ErrSnippetNotInTrash),
+		errors.Is(err, database.ErrGroupNotFound),
+		errors.Is(err, database.ErrSnippetNotInGroup),
+		errors.Is(err, database.ErrLibraryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, database.ErrGroupExists):
+		status = http.StatusConflict
+	case errors.Is(err, database.ErrReadOnlyLibrary):
+		status = http.StatusForbidden
+	case errors.Is(err, database.ErrInvalidCursor),
+		errors.Is(err, database.ErrInvalidImport):
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, err)
+}
+
+// statusRecorder keeps the status code and size of a response for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// logRequests logs every request once it has been handled
+func logRequests(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package daemon
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable, the pid file is still written but not locked
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database/sqlite"
+	"github.com/google/uuid"
+)
+
+// CreateGroup creates a new empty group, group names are unique ignoring case
+func (s *SQLiteHandler) CreateGroup(ctx context.Context, name string, description string) (models.Group, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	_, err := s.queries.GetGroupByName(ctx, name)
+	if err == nil {
+		return models.Group{}, ErrGroupExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Group{}, fmt.Errorf("failed to check for existing group: %w", err)
+	}
+
+	params := sqlite.CreateGroupParams{
+		GroupName:   name,
+		Description: toNullString(description),
+	}
+
+	dbGroup, err := s.queries.CreateGroup(ctx, params)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("failed to create group: %w", err)
+	}
+	return convertSqliteGroupToGroup(dbGroup, nil), nil
+}
+
+// GetGroups returns every group, most recently created first
+func (s *SQLiteHandler) GetGroups(ctx context.Context) ([]models.Group, error) {
+	var groups []models.Group
+
+	dbGroups, err := s.queries.GetAllGroups(ctx)
+	if err != nil {
+		return groups, fmt.Errorf("failed to retrieve groups: %w", err)
+	}
+
+	for _, g := range dbGroups {
+		members, err := s.queries.ListGroupMembers(ctx, g.ID)
+		if err != nil {
+			return groups, fmt.Errorf("failed to retrieve group members: %w", err)
+		}
+		groups = append(groups, convertSqliteGroupToGroup(g, members))
+	}
+	return groups, nil
+}
+
+// GetGroupByName returns the group matching name, ignoring case
+func (s *SQLiteHandler) GetGroupByName(ctx context.Context, name string) (models.Group, error) {
+	dbGroup, err := s.queries.GetGroupByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Group{}, ErrGroupNotFound
+		}
+		return models.Group{}, fmt.Errorf("failed to retrieve group: %w", err)
+	}
+
+	members, err := s.queries.ListGroupMembers(ctx, dbGroup.ID)
+	if err != nil {
+		return models.Group{}, fmt.Errorf("failed to retrieve group members: %w", err)
+	}
+	return convertSqliteGroupToGroup(dbGroup, members), nil
+}
+
+// GetSnippetsByGroup returns the latest version of each snippet in the group, in group order
+func (s *SQLiteHandler) GetSnippetsByGroup(ctx context.Context, name string) ([]models.CodeSnippet, error) {
+	return s.QuerySnippets(ctx, SnippetFilter{Group: name})
+}
+
+// AddSnippetToGroup appends the snippet to the end of the group, adding a snippet already in the group does nothing
+func (s *SQLiteHandler) AddSnippetToGroup(ctx context.Context, name string, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	dbGroup, err := s.queries.GetGroupByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return fmt.Errorf("failed to retrieve group: %w", err)
+	}
+
+	if _, err := s.GetSnippetByUUID(ctx, u); err != nil {
+		return err
+	}
+
+	params := sqlite.AddGroupMemberParams{
+		GroupID:     dbGroup.ID,
+		SnippetUuid: u.String(),
+	}
+	if err := s.queries.AddGroupMember(ctx, params); err != nil {
+		return fmt.Errorf("failed to add snippet to group: %w", err)
+	}
+	return nil
+}
+
+// RemoveSnippetFromGroup removes the snippet from the group
+func (s *SQLiteHandler) RemoveSnippetFromGroup(ctx context.Context, name string, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	dbGroup, err := s.queries.GetGroupByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return fmt.Errorf("failed to retrieve group: %w", err)
+	}
+
+	params := sqlite.RemoveGroupMemberParams{
+		GroupID:     dbGroup.ID,
+		SnippetUuid: u.String(),
+	}
+	removed, err := s.queries.RemoveGroupMember(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to remove snippet from group: %w", err)
+	}
+	if removed == 0 {
+		return ErrSnippetNotInGroup
+	}
+	return nil
+}
+
+// DeleteGroup deletes the group, the snippets in it are not affected
+func (s *SQLiteHandler) DeleteGroup(ctx context.Context, name string) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	dbGroup, err := s.queries.GetGroupByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return fmt.Errorf("failed to retrieve group: %w", err)
+	}
+
+	if err := s.queries.DeleteGroup(ctx, dbGroup.ID); err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+	return nil
+}
+
+// Convert Group (DB) and its member UUIDs -> Group model
+func convertSqliteGroupToGroup(g sqlite.Group, members []string) models.Group {
+	var snippets []uuid.UUID
+	for _, member := range members {
+		parsedUUID, err := uuid.Parse(member)
+		if err != nil {
+			continue // skip malformed entries
+		}
+		snippets = append(snippets, parsedUUID)
+	}
+
+	return models.Group{
+		ID:          g.ID,
+		Name:        g.GroupName,
+		Description: getString(g.Description),
+		Snippets:    snippets,
+		DateAdded:   getTime(g.DateAdded),
+		DateUpdated: getTime(g.DateUpdated),
+	}
+}
@@ -21,6 +21,14 @@ func getTime(nt sql.NullTime) time.Time {
 	return time.Time{} // Returns zero time (0001-01-01 00:00:00 UTC)
 }
 
+// Helper function to extract an optional time.Time from sql.NullTime, NULL is nil
+func getTimePtr(nt sql.NullTime) *time.Time {
+	if nt.Valid {
+		return &nt.Time
+	}
+	return nil
+}
+
 // Helper function to extract an int64 from sql.NullInt64
 func getInt64(ni sql.NullInt64) int64 {
 	if ni.Valid {
@@ -36,3 +44,11 @@ func toNullString(s string) sql.NullString {
 	}
 	return sql.NullString{String: s, Valid: true}
 }
+
+// Helper to convert time.Time to sql.NullTime, a zero time is NULL
+func toNullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{Valid: false}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
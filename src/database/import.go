@@ -0,0 +1,299 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database/sqlite"
+	"github.com/google/uuid"
+)
+
+// ImportStrategy decides what happens to an imported snippet whose uuid is already in the database
+type ImportStrategy int
+
+const (
+	ImportSkip       ImportStrategy = iota // keep the existing snippet as it is
+	ImportOverwrite                        // replace the existing snippet and its history with the imported versions
+	ImportNewVersion                       // add the latest imported version on top of the existing history
+)
+
+// ParseImportStrategy maps the name of a strategy to its value, an empty name is ImportSkip
+func ParseImportStrategy(s string) (ImportStrategy, error) {
+	switch strings.ToLower(s) {
+	case "", "skip":
+		return ImportSkip, nil
+	case "overwrite":
+		return ImportOverwrite, nil
+	case "new-version":
+		return ImportNewVersion, nil
+	}
+	return ImportSkip, fmt.Errorf("invalid conflict strategy %q, expected skip, overwrite or new-version", s)
+}
+
+// ImportOptions controls how snippets are imported
+type ImportOptions struct {
+	Strategy ImportStrategy
+	DryRun   bool // work out the result without keeping any changes
+}
+
+// ImportResult counts what happened to each imported snippet
+type ImportResult struct {
+	Added       int
+	Unchanged   int // already in the database with the same latest version
+	Skipped     int
+	Overwritten int
+	Updated     int         // given a new version
+	Conflicts   []uuid.UUID // snippets already in the database that differed from the import
+}
+
+// custom error used by ImportSnippets when the snippets read from an export are not usable
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportSnippets stores snippets read from an export, keeping their uuids, versions and dates.
+// Versions sharing a uuid form one snippet's history. Everything is imported in a single transaction,
+// so an error leaves the database as it was.
+func (s *SQLiteHandler) ImportSnippets(ctx context.Context, snippets []models.CodeSnippet, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	histories, err := importHistories(snippets)
+	if err != nil {
+		return result, err
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	q := s.queries.WithTx(tx)
+
+	for _, history := range histories {
+		u := history[0].Uuid
+		latest := history[len(history)-1]
+
+		existing, err := q.GetLatestSnippetVersion(ctx, u.String())
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := importHistory(ctx, q, history); err != nil {
+				return result, err
+			}
+			result.Added++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to check for snippet %s: %w", u, err)
+		}
+
+		if !existing.DeletedAt.Valid && sameSnippet(convertSqliteSnippetDetailToCodeSnippet(existing), latest) {
+			result.Unchanged++
+			continue
+		}
+		result.Conflicts = append(result.Conflicts, u)
+
+		switch opts.Strategy {
+		case ImportSkip:
+			result.Skipped++
+		case ImportOverwrite:
+			if err := overwriteHistory(ctx, q, history); err != nil {
+				return result, err
+			}
+			result.Overwritten++
+		case ImportNewVersion:
+			latest.Version = existing.Version + 1
+			if err := appendVersion(ctx, q, existing, latest); err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// importHistories groups snippets by uuid in the order they were first seen, each history oldest version first.
+// Snippets without a uuid are given a new one, and versions without a number follow the numbered ones.
+func importHistories(snippets []models.CodeSnippet) ([][]models.CodeSnippet, error) {
+	var order []uuid.UUID
+	byUUID := map[uuid.UUID][]models.CodeSnippet{}
+	for _, snippet := range snippets {
+		if strings.TrimSpace(snippet.Code) == "" {
+			return nil, fmt.Errorf("%w: snippet %q has no code", ErrInvalidImport, snippet.Name)
+		}
+		if snippet.Uuid == uuid.Nil {
+			snippet.Uuid = uuid.New()
+		}
+		if lang, ok := common.CanonicalLanguage(snippet.Language); ok {
+			snippet.Language = lang
+		}
+		if _, ok := byUUID[snippet.Uuid]; !ok {
+			order = append(order, snippet.Uuid)
+		}
+		byUUID[snippet.Uuid] = append(byUUID[snippet.Uuid], snippet)
+	}
+
+	histories := make([][]models.CodeSnippet, 0, len(order))
+	for _, u := range order {
+		history := byUUID[u]
+		slices.SortStableFunc(history, func(a, b models.CodeSnippet) int {
+			if (a.Version == 0) != (b.Version == 0) {
+				return cmp.Compare(b.Version, a.Version)
+			}
+			return cmp.Compare(a.Version, b.Version)
+		})
+
+		var previous int64
+		for i := range history {
+			if history[i].Version == 0 {
+				history[i].Version = previous + 1
+			}
+			if history[i].Version < 0 {
+				return nil, fmt.Errorf("%w: snippet %s has a negative version", ErrInvalidImport, u)
+			}
+			if history[i].Version == previous {
+				return nil, fmt.Errorf("%w: snippet %s has more than one version %d", ErrInvalidImport, u, previous)
+			}
+			previous = history[i].Version
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
+
+// importHistory inserts every version of a snippet, each superseded by the one after it
+func importHistory(ctx context.Context, q *sqlite.Queries, history []models.CodeSnippet) error {
+	var previous int64
+	for _, snippet := range history {
+		params := sqlite.ImportSnippetParams{
+			Uuid:        snippet.Uuid.String(),
+			Name:        toNullString(snippet.Name),
+			Code:        snippet.Code,
+			Language:    snippet.Language,
+			Description: toNullString(snippet.Description),
+			Source:      toNullString(snippet.Source),
+			DateAdded:   toNullTime(snippet.DateAdded),
+			Version:     snippet.Version,
+		}
+		created, err := q.ImportSnippet(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to insert snippet %s version %d: %w", snippet.Uuid, snippet.Version, err)
+		}
+		if err := addSnippetTags(ctx, q, created.ID, snippet.Tags); err != nil {
+			return err
+		}
+
+		if previous != 0 {
+			supersededParams := sqlite.MarkSnippetSupersededParams{
+				SupersededBy: sql.NullInt64{Int64: created.ID, Valid: true},
+				ID:           previous,
+			}
+			if err := q.MarkSnippetSuperseded(ctx, supersededParams); err != nil {
+				return fmt.Errorf("failed to mark snippet superceded: %w", err)
+			}
+		}
+		previous = created.ID
+	}
+	return nil
+}
+
+// overwriteHistory replaces every stored version of the snippet with the imported ones.
+// Deleting the snippet takes it out of its groups and forgets its usage, both are put back afterwards.
+func overwriteHistory(ctx context.Context, q *sqlite.Queries, history []models.CodeSnippet) error {
+	u := history[0].Uuid.String()
+
+	members, err := q.ListSnippetGroupMembers(ctx, u)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve groups for snippet %s: %w", u, err)
+	}
+	usage, err := q.GetSnippetUsage(ctx, u)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to retrieve usage for snippet %s: %w", u, err)
+	}
+	hasUsage := err == nil
+
+	if err := q.DeleteSnippetByUUID(ctx, u); err != nil {
+		return fmt.Errorf("failed to remove snippet %s: %w", u, err)
+	}
+	if err := importHistory(ctx, q, history); err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		params := sqlite.RestoreGroupMemberParams{
+			GroupID:     m.GroupID,
+			SnippetUuid: m.SnippetUuid,
+			Position:    m.Position,
+			DateAdded:   m.DateAdded,
+		}
+		if err := q.RestoreGroupMember(ctx, params); err != nil {
+			return fmt.Errorf("failed to restore groups for snippet %s: %w", u, err)
+		}
+	}
+	if hasUsage {
+		params := sqlite.RestoreSnippetUsageParams{
+			SnippetUuid: usage.SnippetUuid,
+			UseCount:    usage.UseCount,
+			LastUsed:    usage.LastUsed,
+		}
+		if err := q.RestoreSnippetUsage(ctx, params); err != nil {
+			return fmt.Errorf("failed to restore usage for snippet %s: %w", u, err)
+		}
+	}
+
+	// unused tags of the replaced versions are cleaned up the same way purging a snippet does
+	if err := q.DeleteUnusedTags(ctx); err != nil {
+		return fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+	return nil
+}
+
+// appendVersion stores snippet as the newest version after previous, taking the snippet out of the trash if it was in it
+func appendVersion(ctx context.Context, q *sqlite.Queries, previous sqlite.SnippetDetail, snippet models.CodeSnippet) error {
+	created, err := q.CreateSnippet(ctx, codeSnippetModelToDbCreateSnippetParams(snippet))
+	if err != nil {
+		return fmt.Errorf("failed to insert snippet %s: %w", snippet.Uuid, err)
+	}
+	if err := addSnippetTags(ctx, q, created.ID, snippet.Tags); err != nil {
+		return err
+	}
+
+	supersededParams := sqlite.MarkSnippetSupersededParams{
+		SupersededBy: sql.NullInt64{Int64: created.ID, Valid: true},
+		ID:           previous.ID,
+	}
+	if err := q.MarkSnippetSuperseded(ctx, supersededParams); err != nil {
+		return fmt.Errorf("failed to mark snippet superceded: %w", err)
+	}
+
+	if previous.DeletedAt.Valid {
+		if _, err := q.RestoreSnippetByUUID(ctx, snippet.Uuid.String()); err != nil {
+			return fmt.Errorf("failed to restore snippet %s: %w", snippet.Uuid, err)
+		}
+	}
+	return nil
+}
+
+// sameSnippet reports whether two versions hold the same snippet, ignoring when they were saved
+func sameSnippet(a, b models.CodeSnippet) bool {
+	return a.Name == b.Name &&
+		a.Code == b.Code &&
+		a.Language == b.Language &&
+		a.Description == b.Description &&
+		a.Source == b.Source &&
+		slices.Equal(common.NormaliseTags(a.Tags), common.NormaliseTags(b.Tags))
+}
@@ -1,24 +1,137 @@
 package database
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Ryan-Har/csnip/common/models"
 	"github.com/google/uuid"
 )
 
 // Generic interface for interacting with databases.
+// Every method takes a context so callers can cancel or time limit long running queries.
 type DatabaseInteractions interface {
-	PopulateHelloWorldSnippets() error
-	AddNewSnippet(m models.CodeSnippet) error
-	UpdateSnippet(u uuid.UUID, changedSnippet models.CodeSnippet) (models.CodeSnippet, error)
-	GetSnippets(page int64, limit int64) ([]models.CodeSnippet, error)
-	GetSnippetsByLanguage(lang string) ([]models.CodeSnippet, error)
-	GetSnippetsByTag(tag string) ([]models.CodeSnippet, error)
-	GetSnippetsByLanguageAndTag(lang string, tag string) ([]models.CodeSnippet, error)
-	GetSnippetByUUID(u uuid.UUID) (models.CodeSnippet, error)
-	GetSnippetHistoryByUUID(u uuid.UUID) ([]models.CodeSnippet, error)
-	DeleteSnippetByUUID(u uuid.UUID) error
+	PopulateHelloWorldSnippets(ctx context.Context) error
+	Close() error
+	AddNewSnippet(ctx context.Context, m models.CodeSnippet) (models.CodeSnippet, error)
+	UpdateSnippet(ctx context.Context, u uuid.UUID, changedSnippet models.CodeSnippet) (models.CodeSnippet, error)
+	RevertSnippet(ctx context.Context, u uuid.UUID, version int64) (models.CodeSnippet, error)
+	QuerySnippets(ctx context.Context, filter SnippetFilter) ([]models.CodeSnippet, error)
+	QuerySnippetsPage(ctx context.Context, filter SnippetFilter) (SnippetPage, error)
+	RecordSnippetUse(ctx context.Context, u uuid.UUID) error
+	GetSnippets(ctx context.Context, page int64, limit int64) ([]models.CodeSnippet, error)
+	GetSnippetsByLanguage(ctx context.Context, lang string) ([]models.CodeSnippet, error)
+	GetSnippetsByTag(ctx context.Context, tag string) ([]models.CodeSnippet, error)
+	GetSnippetsByTags(ctx context.Context, tags []string, match TagMatch) ([]models.CodeSnippet, error)
+	GetSnippetsByLanguageAndTags(ctx context.Context, lang string, tags []string, match TagMatch) ([]models.CodeSnippet, error)
+	GetSnippetByUUID(ctx context.Context, u uuid.UUID) (models.CodeSnippet, error)
+	GetSnippetHistoryByUUID(ctx context.Context, u uuid.UUID) ([]models.CodeSnippet, error)
+	DeleteSnippetByUUID(ctx context.Context, u uuid.UUID) error
+	PurgeSnippetByUUID(ctx context.Context, u uuid.UUID) error
+	RestoreSnippetByUUID(ctx context.Context, u uuid.UUID) error
+	GetDeletedSnippets(ctx context.Context) ([]models.CodeSnippet, error)
+	EmptyTrash(ctx context.Context, olderThan time.Duration) (int, error)
+	Search(ctx context.Context, query string, opts SearchOptions) ([]models.SearchResult, error)
+	ListTags(ctx context.Context) ([]models.TagCount, error)
+	CreateGroup(ctx context.Context, name string, description string) (models.Group, error)
+	GetGroups(ctx context.Context) ([]models.Group, error)
+	GetGroupByName(ctx context.Context, name string) (models.Group, error)
+	GetSnippetsByGroup(ctx context.Context, name string) ([]models.CodeSnippet, error)
+	AddSnippetToGroup(ctx context.Context, name string, u uuid.UUID) error
+	RemoveSnippetFromGroup(ctx context.Context, name string, u uuid.UUID) error
+	DeleteGroup(ctx context.Context, name string) error
+	ImportSnippets(ctx context.Context, snippets []models.CodeSnippet, opts ImportOptions) (ImportResult, error)
+}
+
+// TagMatch controls whether a snippet must have any or all of the tags being searched for
+type TagMatch int
+
+const (
+	TagMatchAny TagMatch = iota
+	TagMatchAll
+)
+
+// SnippetFilter narrows a snippet query, every field that is set must match.
+// Name is matched ignoring case anywhere in the name, or as a whole with * and ? wildcards.
+type SnippetFilter struct {
+	Languages      []string // matches any of the languages
+	Tags           []string
+	TagMatch       TagMatch
+	Source         string // matches anywhere in the source
+	Name           string
+	Group          string
+	ModifiedAfter  time.Time // the latest version was saved at or after this time
+	ModifiedBefore time.Time // the latest version was saved before this time
+	Sort           SortOrder
+	Reverse        bool
+	Limit          int64  // zero for no limit
+	Offset         int64  // ignored when Cursor is set
+	Cursor         string // NextCursor of the previous page
+}
+
+// SnippetPage is a single page of snippets matching a filter
+type SnippetPage struct {
+	Snippets   []models.CodeSnippet
+	Total      int64  // number of snippets matching the filter across every page
+	Offset     int64  // number of matching snippets before this page
+	NextCursor string // empty on the last page
+}
+
+// SortOrder is the order snippet queries are returned in
+type SortOrder int
+
+const (
+	SortDefault    SortOrder = iota // group order when filtering by group, otherwise by date
+	SortByDate                      // most recently changed first
+	SortByName                      // alphabetical
+	SortByLanguage                  // alphabetical
+	SortByUsage                     // most used first
+)
+
+// ParseSortOrder maps the name of a sort order to its database value, an empty name is the default order
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return SortDefault, nil
+	case "date":
+		return SortByDate, nil
+	case "name":
+		return SortByName, nil
+	case "language":
+		return SortByLanguage, nil
+	case "usage":
+		return SortByUsage, nil
+	}
+	return SortDefault, fmt.Errorf("invalid sort order %q, expected name, date, language or usage", s)
+}
+
+// SearchOptions narrows a full text search.
+// Matches are wrapped in HighlightStart and HighlightEnd, which default to square brackets.
+type SearchOptions struct {
+	Language       string
+	Limit          int64
+	HighlightStart string
+	HighlightEnd   string
 }
 
 // custom errors used by the above interface, used when no results are found in the sql results set
 var ErrNoSnippetsFound = errors.New("no snippets found for the given parameters")
+
+// custom error used by RevertSnippet when the snippet has no such version
+var ErrVersionNotFound = errors.New("no version of the snippet found with the given number")
+
+// custom error used by the trash methods of the above interface
+var ErrSnippetNotInTrash = errors.New("no snippet in the trash with the given uuid")
+
+// custom error used when a page cursor is malformed or was made for a different sort order
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// custom errors used by the group methods of the above interface
+var (
+	ErrGroupNotFound     = errors.New("no group found with the given name")
+	ErrGroupExists       = errors.New("a group with the given name already exists")
+	ErrSnippetNotInGroup = errors.New("snippet is not a member of the group")
+)
@@ -0,0 +1,558 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/google/uuid"
+)
+
+// Library is a named snippet database read by a LayeredHandler
+type Library struct {
+	Name string
+	DB   DatabaseInteractions
+	// OpenWritable opens the library for writing when DB is read only, CopySnippetTo uses it to add the copy.
+	// It is nil when DB can be written to.
+	OpenWritable func() (DatabaseInteractions, error)
+}
+
+// LibraryInteractions is implemented by handlers that read from more than one library
+type LibraryInteractions interface {
+	Libraries() []string
+	WritableLibrary() string
+	CopySnippetTo(ctx context.Context, u uuid.UUID, library string) (models.CodeSnippet, error)
+}
+
+// custom errors used by the LayeredHandler
+var (
+	ErrLibraryNotFound = errors.New("no library found with the given name")
+	ErrReadOnlyLibrary = errors.New("read only library")
+)
+
+// LayeredHandler merges several libraries into one, listing the snippets of each library in priority order.
+// Every write goes to the writable library, snippets held by the others can be read and copied but not changed.
+// Returned snippets and groups are marked with the name of the library they came from.
+type LayeredHandler struct {
+	libraries []Library
+	writable  Library
+}
+
+// NewLayeredHandler reads from libraries in the order given and writes to the library named writable,
+// the first library is written to if writable is empty
+func NewLayeredHandler(libraries []Library, writable string) (*LayeredHandler, error) {
+	if len(libraries) == 0 {
+		return nil, fmt.Errorf("at least one library is required")
+	}
+	l := &LayeredHandler{libraries: libraries, writable: libraries[0]}
+	if writable != "" {
+		lib, ok := l.library(writable)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrLibraryNotFound, writable)
+		}
+		l.writable = lib
+	}
+	return l, nil
+}
+
+// Libraries returns the name of every library in priority order
+func (l *LayeredHandler) Libraries() []string {
+	names := make([]string, len(l.libraries))
+	for i, lib := range l.libraries {
+		names[i] = lib.Name
+	}
+	return names
+}
+
+// WritableLibrary returns the name of the library that writes go to
+func (l *LayeredHandler) WritableLibrary() string {
+	return l.writable.Name
+}
+
+func (l *LayeredHandler) library(name string) (Library, bool) {
+	for _, lib := range l.libraries {
+		if strings.EqualFold(lib.Name, name) {
+			return lib, true
+		}
+	}
+	return Library{}, false
+}
+
+// CopySnippetTo adds a copy of the latest version of the snippet to library as a new snippet.
+// A library opened read only is opened for writing just for the copy.
+func (l *LayeredHandler) CopySnippetTo(ctx context.Context, u uuid.UUID, library string) (models.CodeSnippet, error) {
+	target, ok := l.library(library)
+	if !ok {
+		return models.CodeSnippet{}, fmt.Errorf("%w: %s", ErrLibraryNotFound, library)
+	}
+	snippet, err := l.GetSnippetByUUID(ctx, u)
+	if err != nil {
+		return models.CodeSnippet{}, err
+	}
+	if strings.EqualFold(snippet.Library, target.Name) {
+		return models.CodeSnippet{}, fmt.Errorf("snippet is already in library %s", target.Name)
+	}
+
+	// copying twice would leave the library with duplicates
+	existing, err := target.DB.QuerySnippets(ctx, SnippetFilter{Name: snippet.Name, Languages: []string{snippet.Language}})
+	if err != nil && !errors.Is(err, ErrNoSnippetsFound) {
+		return models.CodeSnippet{}, fmt.Errorf("library %s: %w", target.Name, err)
+	}
+	for _, e := range existing {
+		if e.Name == snippet.Name && e.Code == snippet.Code {
+			return models.CodeSnippet{}, fmt.Errorf("library %s already has this snippet as %s", target.Name, e.Uuid)
+		}
+	}
+
+	writeDB := target.DB
+	if target.OpenWritable != nil {
+		writeDB, err = target.OpenWritable()
+		if err != nil {
+			return models.CodeSnippet{}, fmt.Errorf("unable to open library %s for writing: %w", target.Name, err)
+		}
+		defer writeDB.Close()
+	}
+
+	copied, err := writeDB.AddNewSnippet(ctx, models.CodeSnippet{
+		Name:        snippet.Name,
+		Code:        snippet.Code,
+		Language:    snippet.Language,
+		Tags:        snippet.Tags,
+		Description: snippet.Description,
+		Source:      snippet.Source,
+	})
+	if err != nil {
+		return models.CodeSnippet{}, fmt.Errorf("failed to copy snippet to library %s: %w", target.Name, err)
+	}
+	return markSnippet(copied, target.Name), nil
+}
+
+func markSnippet(s models.CodeSnippet, library string) models.CodeSnippet {
+	s.Library = library
+	return s
+}
+
+func markSnippets(snippets []models.CodeSnippet, library string) []models.CodeSnippet {
+	for i := range snippets {
+		snippets[i].Library = library
+	}
+	return snippets
+}
+
+// readOnlyError explains a write that failed because the snippet is not in the writable library
+func (l *LayeredHandler) readOnlyError(ctx context.Context, u uuid.UUID, err error) error {
+	if !errors.Is(err, ErrNoSnippetsFound) && !errors.Is(err, ErrSnippetNotInTrash) {
+		return err
+	}
+	for _, lib := range l.libraries {
+		if lib.Name == l.writable.Name {
+			continue
+		}
+		if _, getErr := lib.DB.GetSnippetByUUID(ctx, u); getErr == nil {
+			return fmt.Errorf("snippet belongs to %w %s, copy it with copy-to to change it", ErrReadOnlyLibrary, lib.Name)
+		}
+	}
+	return err
+}
+
+// readOnlyGroupError explains a group write that failed because the group is not in the writable library
+func (l *LayeredHandler) readOnlyGroupError(ctx context.Context, name string, err error) error {
+	if !errors.Is(err, ErrGroupNotFound) {
+		return err
+	}
+	for _, lib := range l.libraries {
+		if lib.Name == l.writable.Name {
+			continue
+		}
+		if _, getErr := lib.DB.GetGroupByName(ctx, name); getErr == nil {
+			return fmt.Errorf("group belongs to %w %s", ErrReadOnlyLibrary, lib.Name)
+		}
+	}
+	return err
+}
+
+func (l *LayeredHandler) PopulateHelloWorldSnippets(ctx context.Context) error {
+	return l.writable.DB.PopulateHelloWorldSnippets(ctx)
+}
+
+// Close closes every library
+func (l *LayeredHandler) Close() error {
+	var errs []error
+	for _, lib := range l.libraries {
+		if err := lib.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close library %s: %w", lib.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *LayeredHandler) AddNewSnippet(ctx context.Context, m models.CodeSnippet) (models.CodeSnippet, error) {
+	snippet, err := l.writable.DB.AddNewSnippet(ctx, m)
+	return markSnippet(snippet, l.writable.Name), err
+}
+
+// ImportSnippets imports into the writable library, conflicts are only looked for there
+func (l *LayeredHandler) ImportSnippets(ctx context.Context, snippets []models.CodeSnippet, opts ImportOptions) (ImportResult, error) {
+	return l.writable.DB.ImportSnippets(ctx, snippets, opts)
+}
+
+func (l *LayeredHandler) UpdateSnippet(ctx context.Context, u uuid.UUID, changedSnippet models.CodeSnippet) (models.CodeSnippet, error) {
+	snippet, err := l.writable.DB.UpdateSnippet(ctx, u, changedSnippet)
+	if err != nil {
+		return snippet, l.readOnlyError(ctx, u, err)
+	}
+	return markSnippet(snippet, l.writable.Name), nil
+}
+
+func (l *LayeredHandler) RevertSnippet(ctx context.Context, u uuid.UUID, version int64) (models.CodeSnippet, error) {
+	snippet, err := l.writable.DB.RevertSnippet(ctx, u, version)
+	if err != nil {
+		return snippet, l.readOnlyError(ctx, u, err)
+	}
+	return markSnippet(snippet, l.writable.Name), nil
+}
+
+// QuerySnippets returns the snippets matching filter from every library, in library priority order
+func (l *LayeredHandler) QuerySnippets(ctx context.Context, filter SnippetFilter) ([]models.CodeSnippet, error) {
+	page, err := l.QuerySnippetsPage(ctx, filter)
+	return page.Snippets, err
+}
+
+// layeredCursor is the position of the next page, the library to continue from and the cursor within it
+type layeredCursor struct {
+	Sort    SortOrder `json:"s"`
+	Reverse bool      `json:"r,omitempty"`
+	Library int       `json:"l"`
+	Cursor  string    `json:"c,omitempty"`
+}
+
+func (c layeredCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// QuerySnippetsPage pages through the snippets of every library in turn, each library sorted by filter.
+// The total and offsets count snippets across every library.
+func (l *LayeredHandler) QuerySnippetsPage(ctx context.Context, filter SnippetFilter) (SnippetPage, error) {
+	var page SnippetPage
+
+	var start layeredCursor
+	if filter.Cursor != "" {
+		b, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil || json.Unmarshal(b, &start) != nil {
+			return page, ErrInvalidCursor
+		}
+		if start.Sort != filter.Sort || start.Reverse != filter.Reverse || start.Library < 0 || start.Library >= len(l.libraries) {
+			return page, ErrInvalidCursor
+		}
+	}
+
+	// count each library first so the page can start in the right library and the offset is across all of them
+	totals := make([]int64, len(l.libraries))
+	groupFound := filter.Group == ""
+	for i, lib := range l.libraries {
+		count := filter
+		count.Limit, count.Offset, count.Cursor = 1, 0, ""
+		p, err := lib.DB.QuerySnippetsPage(ctx, count)
+		if errors.Is(err, ErrGroupNotFound) {
+			continue
+		}
+		if err != nil {
+			return page, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		groupFound = true
+		totals[i] = p.Total
+		page.Total += p.Total
+	}
+	if !groupFound {
+		return page, ErrGroupNotFound
+	}
+
+	first, innerOffset := start.Library, int64(0)
+	if filter.Cursor != "" {
+		for _, total := range totals[:first] {
+			page.Offset += total
+		}
+	} else {
+		page.Offset = max(filter.Offset, 0)
+		first, innerOffset = len(l.libraries), page.Offset
+		for i, total := range totals {
+			if innerOffset < total {
+				first = i
+				break
+			}
+			innerOffset -= total
+		}
+	}
+
+	for i := first; i < len(l.libraries); i++ {
+		if filter.Limit > 0 && int64(len(page.Snippets)) == filter.Limit {
+			if totals[i] > 0 {
+				page.NextCursor = layeredCursor{Sort: filter.Sort, Reverse: filter.Reverse, Library: i}.encode()
+				break
+			}
+			continue
+		}
+		if totals[i] == 0 {
+			continue
+		}
+
+		inner := filter
+		inner.Offset, inner.Cursor = 0, ""
+		if i == first {
+			inner.Offset = innerOffset
+			if filter.Cursor != "" {
+				inner.Cursor = start.Cursor
+			}
+		}
+		if filter.Limit > 0 {
+			inner.Limit = filter.Limit - int64(len(page.Snippets))
+		}
+
+		lib := l.libraries[i]
+		p, err := lib.DB.QuerySnippetsPage(ctx, inner)
+		if err != nil && !errors.Is(err, ErrNoSnippetsFound) {
+			return page, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		if i == first && filter.Cursor != "" {
+			page.Offset += p.Offset
+		}
+		page.Snippets = append(page.Snippets, markSnippets(p.Snippets, lib.Name)...)
+
+		if p.NextCursor != "" {
+			page.NextCursor = layeredCursor{Sort: filter.Sort, Reverse: filter.Reverse, Library: i, Cursor: p.NextCursor}.encode()
+			break
+		}
+	}
+	return page, nil
+}
+
+// RecordSnippetUse counts a use of a snippet in the writable library, uses of snippets in other libraries are not recorded
+func (l *LayeredHandler) RecordSnippetUse(ctx context.Context, u uuid.UUID) error {
+	snippet, err := l.GetSnippetByUUID(ctx, u)
+	if err != nil {
+		return err
+	}
+	if snippet.Library != l.writable.Name {
+		return nil
+	}
+	return l.writable.DB.RecordSnippetUse(ctx, u)
+}
+
+func (l *LayeredHandler) GetSnippets(ctx context.Context, page int64, limit int64) ([]models.CodeSnippet, error) {
+	return l.QuerySnippets(ctx, SnippetFilter{
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+}
+
+func (l *LayeredHandler) GetSnippetsByLanguage(ctx context.Context, lang string) ([]models.CodeSnippet, error) {
+	return l.QuerySnippets(ctx, SnippetFilter{Languages: []string{lang}})
+}
+
+func (l *LayeredHandler) GetSnippetsByTag(ctx context.Context, tag string) ([]models.CodeSnippet, error) {
+	return l.GetSnippetsByTags(ctx, []string{tag}, TagMatchAny)
+}
+
+func (l *LayeredHandler) GetSnippetsByTags(ctx context.Context, tags []string, match TagMatch) ([]models.CodeSnippet, error) {
+	if len(common.NormaliseTags(tags)) == 0 {
+		return nil, nil
+	}
+	return l.QuerySnippets(ctx, SnippetFilter{Tags: tags, TagMatch: match})
+}
+
+func (l *LayeredHandler) GetSnippetsByLanguageAndTags(ctx context.Context, lang string, tags []string, match TagMatch) ([]models.CodeSnippet, error) {
+	return l.QuerySnippets(ctx, SnippetFilter{Languages: []string{lang}, Tags: tags, TagMatch: match})
+}
+
+// GetSnippetByUUID returns the snippet from the first library holding it
+func (l *LayeredHandler) GetSnippetByUUID(ctx context.Context, u uuid.UUID) (models.CodeSnippet, error) {
+	for _, lib := range l.libraries {
+		snippet, err := lib.DB.GetSnippetByUUID(ctx, u)
+		if errors.Is(err, ErrNoSnippetsFound) {
+			continue
+		}
+		if err != nil {
+			return snippet, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		return markSnippet(snippet, lib.Name), nil
+	}
+	return models.CodeSnippet{}, ErrNoSnippetsFound
+}
+
+// GetSnippetHistoryByUUID returns the history of the snippet from the first library holding it
+func (l *LayeredHandler) GetSnippetHistoryByUUID(ctx context.Context, u uuid.UUID) ([]models.CodeSnippet, error) {
+	for _, lib := range l.libraries {
+		history, err := lib.DB.GetSnippetHistoryByUUID(ctx, u)
+		if errors.Is(err, ErrNoSnippetsFound) || (err == nil && len(history) == 0) {
+			continue
+		}
+		if err != nil {
+			return history, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		return markSnippets(history, lib.Name), nil
+	}
+	return nil, ErrNoSnippetsFound
+}
+
+func (l *LayeredHandler) DeleteSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	if err := l.writable.DB.DeleteSnippetByUUID(ctx, u); err != nil {
+		return l.readOnlyError(ctx, u, err)
+	}
+	return nil
+}
+
+func (l *LayeredHandler) PurgeSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	if err := l.writable.DB.PurgeSnippetByUUID(ctx, u); err != nil {
+		return l.readOnlyError(ctx, u, err)
+	}
+	return nil
+}
+
+func (l *LayeredHandler) RestoreSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	return l.writable.DB.RestoreSnippetByUUID(ctx, u)
+}
+
+// GetDeletedSnippets returns the trash of every library
+func (l *LayeredHandler) GetDeletedSnippets(ctx context.Context) ([]models.CodeSnippet, error) {
+	var snippets []models.CodeSnippet
+	for _, lib := range l.libraries {
+		deleted, err := lib.DB.GetDeletedSnippets(ctx)
+		if err != nil {
+			return snippets, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		snippets = append(snippets, markSnippets(deleted, lib.Name)...)
+	}
+	return snippets, nil
+}
+
+// EmptyTrash empties the trash of the writable library only
+func (l *LayeredHandler) EmptyTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	return l.writable.DB.EmptyTrash(ctx, olderThan)
+}
+
+// Search searches every library, results are ordered by rank across all of them
+func (l *LayeredHandler) Search(ctx context.Context, query string, opts SearchOptions) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	for _, lib := range l.libraries {
+		found, err := lib.DB.Search(ctx, query, opts)
+		if err != nil {
+			return results, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		for _, r := range found {
+			r.Library = lib.Name
+			results = append(results, r)
+		}
+	}
+
+	// stable so equal ranks keep library priority order
+	slices.SortStableFunc(results, func(a, b models.SearchResult) int {
+		switch {
+		case a.Rank < b.Rank:
+			return -1
+		case a.Rank > b.Rank:
+			return 1
+		}
+		return 0
+	})
+	if opts.Limit > 0 && int64(len(results)) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+// ListTags returns every tag across all libraries, most used first
+func (l *LayeredHandler) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	counts := map[string]int64{}
+	for _, lib := range l.libraries {
+		tags, err := lib.DB.ListTags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		for _, t := range tags {
+			counts[t.Name] += t.Count
+		}
+	}
+
+	var tags []models.TagCount
+	for name, count := range counts {
+		tags = append(tags, models.TagCount{Name: name, Count: count})
+	}
+	slices.SortFunc(tags, func(a, b models.TagCount) int {
+		if a.Count != b.Count {
+			return int(b.Count - a.Count)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tags, nil
+}
+
+func (l *LayeredHandler) CreateGroup(ctx context.Context, name string, description string) (models.Group, error) {
+	group, err := l.writable.DB.CreateGroup(ctx, name, description)
+	group.Library = l.writable.Name
+	return group, err
+}
+
+// GetGroups returns the groups of every library in priority order
+func (l *LayeredHandler) GetGroups(ctx context.Context) ([]models.Group, error) {
+	var groups []models.Group
+	for _, lib := range l.libraries {
+		found, err := lib.DB.GetGroups(ctx)
+		if err != nil {
+			return groups, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		for _, g := range found {
+			g.Library = lib.Name
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// GetGroupByName returns the group from the first library holding one with the name
+func (l *LayeredHandler) GetGroupByName(ctx context.Context, name string) (models.Group, error) {
+	for _, lib := range l.libraries {
+		group, err := lib.DB.GetGroupByName(ctx, name)
+		if errors.Is(err, ErrGroupNotFound) {
+			continue
+		}
+		if err != nil {
+			return group, fmt.Errorf("library %s: %w", lib.Name, err)
+		}
+		group.Library = lib.Name
+		return group, nil
+	}
+	return models.Group{}, ErrGroupNotFound
+}
+
+func (l *LayeredHandler) GetSnippetsByGroup(ctx context.Context, name string) ([]models.CodeSnippet, error) {
+	return l.QuerySnippets(ctx, SnippetFilter{Group: name})
+}
+
+func (l *LayeredHandler) AddSnippetToGroup(ctx context.Context, name string, u uuid.UUID) error {
+	if err := l.writable.DB.AddSnippetToGroup(ctx, name, u); err != nil {
+		return l.readOnlyGroupError(ctx, name, err)
+	}
+	return nil
+}
+
+func (l *LayeredHandler) RemoveSnippetFromGroup(ctx context.Context, name string, u uuid.UUID) error {
+	if err := l.writable.DB.RemoveSnippetFromGroup(ctx, name, u); err != nil {
+		return l.readOnlyGroupError(ctx, name, err)
+	}
+	return nil
+}
+
+func (l *LayeredHandler) DeleteGroup(ctx context.Context, name string) error {
+	if err := l.writable.DB.DeleteGroup(ctx, name); err != nil {
+		return l.readOnlyGroupError(ctx, name, err)
+	}
+	return nil
+}
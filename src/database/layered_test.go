@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+// newLayeredFixture layers a personal library of three snippets and an empty library over the paging fixture,
+// which is the only library with the group
+func newLayeredFixture(t *testing.T) (*LayeredHandler, *SQLiteHandler, pagingFixture) {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	personal := newTestHandler(t, filepath.Join(dir, "personal.db"))
+	for _, name := range []string{"beta", "alpha", "beta"} {
+		if _, err := personal.AddNewSnippet(ctx, models.CodeSnippet{Name: name, Code: "echo " + name, Language: "go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	empty := newTestHandler(t, filepath.Join(dir, "empty.db"))
+	team := newPagingFixture(t)
+
+	l, err := NewLayeredHandler([]Library{
+		{Name: "personal", DB: personal},
+		{Name: "empty", DB: empty},
+		{Name: "team", DB: team.db},
+	}, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, personal, team
+}
+
+// TestLayeredQuerySnippetsPageCursor walks pages that cross from one library into the next, past an empty one,
+// and checks every snippet of each library is listed once in library order
+func TestLayeredQuerySnippetsPageCursor(t *testing.T) {
+	ctx := context.Background()
+	l, personal, team := newLayeredFixture(t)
+
+	for _, tt := range pagingFilters {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []models.CodeSnippet
+			for _, lib := range []struct {
+				name string
+				db   *SQLiteHandler
+			}{{"personal", personal}, {"team", team.db}} {
+				snippets, err := lib.db.QuerySnippets(ctx, tt.filter)
+				if errors.Is(err, ErrGroupNotFound) {
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, markSnippets(snippets, lib.name)...)
+			}
+
+			all, err := l.QuerySnippets(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSameSnippets(t, all, want)
+
+			for limit := int64(1); limit <= int64(len(want))+1; limit++ {
+				filter := tt.filter
+				filter.Limit = limit
+				checkSameSnippets(t, walkPages(t, l, filter, len(want)), want)
+
+				var byOffset []models.CodeSnippet
+				for filter.Offset = 0; filter.Offset < int64(len(want)); filter.Offset += limit {
+					page, err := l.QuerySnippetsPage(ctx, filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if page.Offset != filter.Offset {
+						t.Errorf("page has offset %d, want %d", page.Offset, filter.Offset)
+					}
+					byOffset = append(byOffset, page.Snippets...)
+				}
+				checkSameSnippets(t, byOffset, want)
+			}
+		})
+	}
+}
+
+func TestLayeredQuerySnippetsPageInvalidCursor(t *testing.T) {
+	ctx := context.Background()
+	l, _, _ := newLayeredFixture(t)
+
+	page, err := l.QuerySnippetsPage(ctx, SnippetFilter{Sort: SortByName, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cursor of a library within the layered cursor is checked by the library
+	inner, err := l.libraries[0].DB.QuerySnippetsPage(ctx, SnippetFilter{Sort: SortByDate, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter SnippetFilter
+	}{
+		{"other sort order", SnippetFilter{Sort: SortByDate, Cursor: page.NextCursor}},
+		{"reversed", SnippetFilter{Sort: SortByName, Reverse: true, Cursor: page.NextCursor}},
+		{"library out of range", SnippetFilter{Sort: SortByName, Cursor: layeredCursor{Sort: SortByName, Library: 3}.encode()}},
+		{"negative library", SnippetFilter{Sort: SortByName, Cursor: layeredCursor{Sort: SortByName, Library: -1}.encode()}},
+		{"inner cursor of another sort order", SnippetFilter{Sort: SortByName, Cursor: layeredCursor{Sort: SortByName, Cursor: inner.NextCursor}.encode()}},
+		{"not base64", SnippetFilter{Sort: SortByName, Cursor: "not a cursor!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.QuerySnippetsPage(ctx, tt.filter); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+// TestLayeredReadOnlyLibrary checks writes to another library's snippets and groups fail with ErrReadOnlyLibrary,
+// and that copy-to still adds to a library opened read only through OpenWritable
+func TestLayeredReadOnlyLibrary(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	personal := newTestHandler(t, filepath.Join(dir, "personal.db"))
+	teamLoc := filepath.Join(dir, "team.db")
+	writable := newTestHandler(t, teamLoc)
+	shared, err := writable.AddNewSnippet(ctx, models.CodeSnippet{Name: "shared", Code: "echo shared", Language: "bash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writable.CreateGroup(ctx, "team group", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	team, err := NewReadOnlySQLiteHandler(teamLoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { team.Close() })
+	l, err := NewLayeredHandler([]Library{
+		{Name: "personal", DB: personal},
+		{Name: "team", DB: team, OpenWritable: func() (DatabaseInteractions, error) { return NewSQLiteHandler(teamLoc) }},
+	}, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.UpdateSnippet(ctx, shared.Uuid, models.CodeSnippet{Code: "echo changed"}); !errors.Is(err, ErrReadOnlyLibrary) {
+		t.Errorf("updating a team snippet: got error %v, want ErrReadOnlyLibrary", err)
+	}
+	if err := l.DeleteGroup(ctx, "team group"); !errors.Is(err, ErrReadOnlyLibrary) {
+		t.Errorf("deleting a team group: got error %v, want ErrReadOnlyLibrary", err)
+	}
+
+	mine, err := l.AddNewSnippet(ctx, models.CodeSnippet{Name: "mine", Code: "echo mine", Language: "bash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := l.CopySnippetTo(ctx, mine.Uuid, "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Library != "team" || copied.Code != mine.Code {
+		t.Errorf("copied snippet is %+v, want the code of %s in team", copied, mine.Uuid)
+	}
+	if _, err := team.GetSnippetByUUID(ctx, copied.Uuid); err != nil {
+		t.Errorf("copy is not in the team library: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the ordered up-migrations for the sqlite schema.
+// Files are named <version>_<description>.sql and are also used by sqlc as the schema source.
+//
+//go:embed sqlite/migrations/*.sql
+var migrationFiles embed.FS
+
+// custom errors returned while migrating the database schema
+var (
+	ErrDatabaseTooNew = errors.New("database schema is newer than this version of csnip supports")
+	ErrSchemaMismatch = errors.New("database schema does not match this version of csnip")
+)
+
+type migration struct {
+	version int32
+	name    string
+	sql     string
+}
+
+// loadMigrations reads the embedded migrations and returns them ordered by version
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "sqlite/migrations")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, found := strings.Cut(name, "_")
+		if !found || path.Ext(name) != ".sql" {
+			return nil, fmt.Errorf("migration file %q is not named <version>_<description>.sql", name)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %q does not start with a version number", name)
+		}
+
+		content, err := migrationFiles.ReadFile("sqlite/migrations/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration %q: %w", name, err)
+		}
+
+		migrations = append(migrations, migration{
+			version: int32(version),
+			name:    name,
+			sql:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	for i, m := range migrations {
+		if m.version != int32(i+1) {
+			return nil, fmt.Errorf("migration %q is out of sequence, expected version %d", m.name, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// migrateSQLiteDB brings the schema up to the latest embedded migration and returns the resulting version.
+// The schema version is tracked with PRAGMA user_version. It is read again and every pending migration applied in
+// one BEGIN IMMEDIATE transaction, so two processes opening an old database cannot both migrate it and a failed
+// migration leaves the database as it was. Existing databases are backed up before any migration is applied.
+func migrateSQLiteDB(db *sql.DB, dbLoc string) (int32, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	latest := migrations[len(migrations)-1].version
+
+	// checked without the write lock first so opening an up to date database does not wait on other writers
+	current, err := schemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > latest {
+		return current, fmt.Errorf("%w: database is at version %d, latest known version is %d", ErrDatabaseTooNew, current, latest)
+	}
+	if current == latest {
+		return current, nil
+	}
+
+	// VACUUM INTO cannot run inside a transaction, so the backup is taken before the lock
+	if current > 0 {
+		backupLoc, err := backupSQLiteDB(db, dbLoc, current)
+		if err != nil {
+			return current, err
+		}
+		if backupLoc != "" {
+			log.Printf("migrating database from version %d to %d, backup written to %s", current, latest, backupLoc)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return current, fmt.Errorf("failed to start transaction for migrations: %w", err)
+	}
+	defer tx.Rollback()
+
+	// another process may have migrated the database while the backup was written
+	if current, err = schemaVersion(tx); err != nil {
+		return 0, err
+	}
+	if current > latest {
+		return current, fmt.Errorf("%w: database is at version %d, latest known version is %d", ErrDatabaseTooNew, current, latest)
+	}
+
+	version := current
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if _, err := tx.Exec(m.sql); err != nil {
+			return current, fmt.Errorf("failed to apply migration %q: %w", m.name, err)
+		}
+		version = m.version
+	}
+	if err := setUserVersion(tx, version); err != nil {
+		return current, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return current, fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return version, nil
+}
+
+// schemaVersion returns the version recorded in user_version. Databases created by hand from the original
+// schema.sql have tables but no recorded version, they are at version 1.
+func schemaVersion(db execQuerier) (int32, error) {
+	version, err := getUserVersion(db)
+	if err != nil || version != 0 {
+		return version, err
+	}
+	exists, err := tableExists(db, "snippets")
+	if err != nil || !exists {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// checkSchemaVersion returns the schema version of a database that is opened read only, which cannot be migrated
+func checkSchemaVersion(db *sql.DB) (int32, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	latest := migrations[len(migrations)-1].version
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > latest {
+		return current, fmt.Errorf("%w: database is at version %d, latest known version is %d", ErrDatabaseTooNew, current, latest)
+	}
+	if current != latest {
+		return current, fmt.Errorf("%w: database is at version %d and must be migrated to version %d, set it as write_library to migrate it",
+			ErrSchemaMismatch, current, latest)
+	}
+	return current, nil
+}
+
+// backupSQLiteDB writes a consistent copy of the database next to the original file.
+// In memory databases are not backed up and an empty location is returned.
+func backupSQLiteDB(db *sql.DB, dbLoc string, version int32) (string, error) {
+	if isMemoryDB(dbLoc) {
+		return "", nil
+	}
+
+	backupLoc := fmt.Sprintf("%s.v%d-%s.bak", dbLoc, version, time.Now().Format("20060102T150405"))
+	if _, err := db.Exec("VACUUM INTO ?", backupLoc); err != nil {
+		return "", fmt.Errorf("unable to back up database before migrating: %w", err)
+	}
+	return backupLoc, nil
+}
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx
+type execQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func getUserVersion(db execQuerier) (int32, error) {
+	var version int32
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("unable to read database schema version: %w", err)
+	}
+	return version, nil
+}
+
+func setUserVersion(db execQuerier, version int32) error {
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return fmt.Errorf("unable to set database schema version: %w", err)
+	}
+	return nil
+}
+
+func tableExists(db execQuerier, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("unable to inspect database schema: %w", err)
+	}
+	return count > 0, nil
+}
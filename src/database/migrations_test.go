@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+// latestVersion is the version of the newest embedded migration
+func latestVersion(t *testing.T) int32 {
+	t.Helper()
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// createRawDB runs statements against a new database at dbLoc without migrating it
+func createRawDB(t *testing.T, dbLoc string, statements ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", dbLoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+}
+
+func backups(t *testing.T, dbLoc string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(dbLoc + ".v*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	dbLoc := filepath.Join(t.TempDir(), "csnip.db")
+	db := newTestHandler(t, dbLoc)
+
+	if db.version != latestVersion(t) {
+		t.Fatalf("fresh database is at version %d, want %d", db.version, latestVersion(t))
+	}
+	version, err := getUserVersion(db.database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != db.version {
+		t.Fatalf("user_version is %d, want %d", version, db.version)
+	}
+	if found := backups(t, dbLoc); len(found) != 0 {
+		t.Fatalf("a new database was backed up to %v", found)
+	}
+
+	if _, err := db.AddNewSnippet(context.Background(), models.CodeSnippet{Name: "fresh", Code: "echo", Language: "bash", Tags: []string{"new"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMigrateBaselineDatabase adopts a database created by hand from the original schema.sql,
+// which has tables but no recorded version
+func TestMigrateBaselineDatabase(t *testing.T) {
+	ctx := context.Background()
+	dbLoc := filepath.Join(t.TempDir(), "csnip.db")
+
+	schema, err := migrationFiles.ReadFile("sqlite/migrations/0001_initial_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createRawDB(t, dbLoc, string(schema),
+		`INSERT INTO snippets (uuid, name, code, language, tags) VALUES ('1b4e28ba-2fa1-11d2-883f-0016d3cca427', 'baseline', 'ls -la', 'bash', 'files,shell')`)
+
+	db := newTestHandler(t, dbLoc)
+	if db.version != latestVersion(t) {
+		t.Fatalf("baseline database is at version %d, want %d", db.version, latestVersion(t))
+	}
+	if found := backups(t, dbLoc); len(found) != 1 {
+		t.Fatalf("found backups %v, want one", found)
+	}
+
+	snippets, err := db.QuerySnippets(ctx, SnippetFilter{Name: "baseline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippets) != 1 || snippets[0].Code != "ls -la" {
+		t.Fatalf("baseline snippet not kept, got %+v", snippets)
+	}
+	if tags := snippets[0].Tags; len(tags) != 2 || tags[0] != "files" || tags[1] != "shell" {
+		t.Fatalf("baseline tags are %v, want [files shell]", tags)
+	}
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	dbLoc := filepath.Join(t.TempDir(), "csnip.db")
+	db, err := NewSQLiteHandler(dbLoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	newer := latestVersion(t) + 1
+	createRawDB(t, dbLoc, fmt.Sprintf("PRAGMA user_version = %d", newer))
+
+	_, err = NewSQLiteHandler(dbLoc)
+	if !errors.Is(err, ErrDatabaseTooNew) {
+		t.Fatalf("opening a database at version %d returned %v, want %v", newer, err, ErrDatabaseTooNew)
+	}
+	if found := backups(t, dbLoc); len(found) != 0 {
+		t.Fatalf("a database that was refused was backed up to %v", found)
+	}
+}
@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database/sqlite"
+)
+
+// QuerySnippets returns the latest version of every snippet matching all of the filters set in filter.
+// Snippets in the trash are never returned.
+func (s *SQLiteHandler) QuerySnippets(ctx context.Context, filter SnippetFilter) ([]models.CodeSnippet, error) {
+	page, err := s.querySnippetPage(ctx, filter, false)
+	return page.Snippets, err
+}
+
+// QuerySnippetsPage returns a single page of QuerySnippets along with the total number of matching snippets.
+// Pages can be fetched by Offset, or by passing NextCursor back as the filter's Cursor which keeps pages
+// stable while snippets are being added or changed.
+func (s *SQLiteHandler) QuerySnippetsPage(ctx context.Context, filter SnippetFilter) (SnippetPage, error) {
+	return s.querySnippetPage(ctx, filter, true)
+}
+
+// snippetQuery is the FROM and WHERE clauses of a snippet query, along with their arguments
+type snippetQuery struct {
+	from       string
+	conditions []string
+	args       []interface{}
+}
+
+func (q snippetQuery) where() string {
+	where := "\nWHERE sd.superseded_by IS NULL\nAND sd.deleted_at IS NULL"
+	for _, condition := range q.conditions {
+		where += "\nAND " + condition
+	}
+	return where
+}
+
+// snippetCursor is the position of the last snippet on a page, encoded as an opaque string for callers
+type snippetCursor struct {
+	Sort    SortOrder `json:"s"`
+	Reverse bool      `json:"r,omitempty"`
+	Text    string    `json:"t,omitempty"`
+	Number  int64     `json:"n,omitempty"`
+	ID      int64     `json:"i"`
+}
+
+func (c snippetCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSnippetCursor(s string) (snippetCursor, error) {
+	var c snippetCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (s *SQLiteHandler) querySnippetPage(ctx context.Context, filter SnippetFilter, count bool) (SnippetPage, error) {
+	var page SnippetPage
+
+	q, err := s.buildSnippetQuery(ctx, filter)
+	if err != nil {
+		return page, err
+	}
+
+	key, numericKey := filter.sortKey()
+	direction := "ASC"
+	if filter.descending() {
+		direction = "DESC"
+	}
+
+	if count {
+		countQuery := "SELECT COUNT(*) FROM " + q.from + q.where()
+		if err := s.database.QueryRowContext(ctx, countQuery, q.args...).Scan(&page.Total); err != nil {
+			return page, fmt.Errorf("failed to count snippets: %w", err)
+		}
+	}
+
+	// keyset pagination, continue after the last snippet of the previous page in the same order
+	args := q.args
+	where := q.where()
+	if filter.Cursor != "" {
+		cursor, err := decodeSnippetCursor(filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		if cursor.Sort != filter.Sort || cursor.Reverse != filter.Reverse {
+			return page, ErrInvalidCursor
+		}
+
+		var cursorKey interface{} = cursor.Text
+		if numericKey {
+			cursorKey = cursor.Number
+		}
+		op := ">"
+		if filter.descending() {
+			op = "<"
+		}
+		keyset := fmt.Sprintf("\nAND (%[1]s %[2]s ? OR (%[1]s = ? AND sd.id %[2]s ?))", key, op)
+
+		if count {
+			// the number of snippets before the cursor is the offset of this page
+			before := strings.NewReplacer(">", "<=", "<", ">=").Replace(op)
+			beforeQuery := "SELECT COUNT(*) FROM " + q.from + where +
+				fmt.Sprintf("\nAND (%[1]s %[2]s ? AND NOT (%[1]s = ? AND sd.id %[3]s ?))", key, before, op)
+			beforeArgs := append(append([]interface{}{}, args...), cursorKey, cursorKey, cursor.ID)
+			if err := s.database.QueryRowContext(ctx, beforeQuery, beforeArgs...).Scan(&page.Offset); err != nil {
+				return page, fmt.Errorf("failed to count snippets: %w", err)
+			}
+		}
+
+		where += keyset
+		args = append(append([]interface{}{}, args...), cursorKey, cursorKey, cursor.ID)
+	} else {
+		page.Offset = max(filter.Offset, 0)
+	}
+
+	sqlQuery := "SELECT sd.id, sd.uuid, sd.name, sd.code, sd.language, sd.description, sd.source, sd.date_added, sd.version, sd.superseded_by, sd.tags, sd.deleted_at, " + key +
+		"\nFROM " + q.from + where +
+		"\nORDER BY " + key + " " + direction + ", sd.id " + direction
+
+	// one extra row is fetched to find out whether there is another page
+	if filter.Limit > 0 {
+		sqlQuery += "\nLIMIT ?"
+		args = append(args, filter.Limit+1)
+		if filter.Cursor == "" {
+			sqlQuery += " OFFSET ?"
+			args = append(args, page.Offset)
+		}
+	}
+
+	rows, err := s.database.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return page, fmt.Errorf("failed to retrieve snippets: %w", err)
+	}
+	defer rows.Close()
+
+	var last snippetCursor
+	for rows.Next() {
+		var i sqlite.SnippetDetail
+		var sortKey interface{}
+		if err := rows.Scan(
+			&i.ID,
+			&i.Uuid,
+			&i.Name,
+			&i.Code,
+			&i.Language,
+			&i.Description,
+			&i.Source,
+			&i.DateAdded,
+			&i.Version,
+			&i.SupersededBy,
+			&i.Tags,
+			&i.DeletedAt,
+			&sortKey,
+		); err != nil {
+			return page, fmt.Errorf("failed to read snippets: %w", err)
+		}
+
+		if filter.Limit > 0 && int64(len(page.Snippets)) == filter.Limit {
+			page.NextCursor = last.encode()
+			break
+		}
+
+		last = snippetCursor{Sort: filter.Sort, Reverse: filter.Reverse, ID: i.ID}
+		switch k := sortKey.(type) {
+		case int64:
+			last.Number = k
+		case string:
+			last.Text = k
+		case []byte:
+			last.Text = string(k)
+		}
+		page.Snippets = append(page.Snippets, convertSqliteSnippetDetailToCodeSnippet(i))
+	}
+	if err := rows.Err(); err != nil {
+		return page, fmt.Errorf("failed to read snippets: %w", err)
+	}
+
+	return page, nil
+}
+
+// buildSnippetQuery turns the filters set in filter into a FROM clause and conditions
+func (s *SQLiteHandler) buildSnippetQuery(ctx context.Context, filter SnippetFilter) (snippetQuery, error) {
+	q := snippetQuery{from: "snippet_details sd"}
+
+	if filter.Group != "" {
+		dbGroup, err := s.queries.GetGroupByName(ctx, filter.Group)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return q, ErrGroupNotFound
+			}
+			return q, fmt.Errorf("failed to retrieve group: %w", err)
+		}
+		q.from += "\nJOIN group_members gm ON gm.snippet_uuid = sd.uuid AND gm.group_id = ?"
+		q.args = append(q.args, dbGroup.ID)
+	}
+
+	if filter.Sort == SortByUsage {
+		q.from += "\nLEFT JOIN snippet_usage su ON su.snippet_uuid = sd.uuid"
+	}
+
+	if langs := languageVariants(filter.Languages...); len(langs) > 0 {
+		q.conditions = append(q.conditions, "LOWER(sd.language) IN ("+placeholders(len(langs), "LOWER(?)")+")")
+		for _, lang := range langs {
+			q.args = append(q.args, lang)
+		}
+	}
+
+	if tags := common.NormaliseTags(filter.Tags); len(tags) > 0 {
+		q.conditions = append(q.conditions, `sd.id IN (
+    SELECT st.snippet_id FROM snippet_tags st
+    JOIN tags t ON t.id = st.tag_id
+    WHERE t.name IN (`+placeholders(len(tags), "?")+`)
+    GROUP BY st.snippet_id
+    HAVING COUNT(*) >= ?
+)`)
+		for _, tag := range tags {
+			q.args = append(q.args, tag)
+		}
+		q.args = append(q.args, minTagMatches(tags, filter.TagMatch))
+	}
+
+	if filter.Source != "" {
+		q.conditions = append(q.conditions, "instr(LOWER(sd.source), LOWER(?)) > 0")
+		q.args = append(q.args, filter.Source)
+	}
+
+	if filter.Name != "" {
+		q.conditions = append(q.conditions, `sd.name LIKE ? ESCAPE '\'`)
+		q.args = append(q.args, namePatternToLike(filter.Name))
+	}
+
+	// date_added is stored by sqlite in UTC
+	if !filter.ModifiedAfter.IsZero() {
+		q.conditions = append(q.conditions, "sd.date_added >= ?")
+		q.args = append(q.args, filter.ModifiedAfter.UTC())
+	}
+	if !filter.ModifiedBefore.IsZero() {
+		q.conditions = append(q.conditions, "sd.date_added < ?")
+		q.args = append(q.args, filter.ModifiedBefore.UTC())
+	}
+
+	return q, nil
+}
+
+// sortKey returns the expression snippets are ordered by, ties are broken by id in the same direction.
+// numeric reports whether the expression is an integer rather than text.
+func (f SnippetFilter) sortKey() (key string, numeric bool) {
+	switch f.Sort {
+	case SortByName:
+		return "COALESCE(sd.name, '') COLLATE NOCASE", false
+	case SortByLanguage:
+		return "sd.language COLLATE NOCASE", false
+	case SortByUsage:
+		return "COALESCE(su.use_count, 0)", true
+	case SortDefault:
+		if f.Group != "" {
+			return "gm.position", true
+		}
+	}
+	// each version gets a new id, so id order is the order snippets were last changed
+	return "sd.id", true
+}
+
+// descending reports whether the filter's sort order runs from high to low
+func (f SnippetFilter) descending() bool {
+	switch f.Sort {
+	case SortByName, SortByLanguage:
+		return f.Reverse
+	case SortDefault:
+		if f.Group != "" {
+			return f.Reverse
+		}
+	}
+	// newest and most used first unless reversed
+	return !f.Reverse
+}
+
+// languageVariants returns the languages along with their canonical names, so that an alias matches
+// snippets stored under the canonical name and snippets stored with the alias before languages were canonicalised
+func languageVariants(langs ...string) []string {
+	var variants []string
+	for _, lang := range langs {
+		variants = append(variants, lang)
+		if canonical, ok := common.CanonicalLanguage(lang); ok && !strings.EqualFold(canonical, lang) {
+			variants = append(variants, canonical)
+		}
+	}
+	return variants
+}
+
+// placeholders returns n comma separated copies of placeholder
+func placeholders(n int, placeholder string) string {
+	return strings.TrimSuffix(strings.Repeat(placeholder+", ", n), ", ")
+}
+
+// namePatternToLike converts a name pattern into a LIKE pattern.
+// * and ? match any run of characters or a single character, a pattern without either matches anywhere in the name.
+func namePatternToLike(pattern string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`, `?`, `_`)
+	like := replacer.Replace(pattern)
+	if !strings.ContainsAny(pattern, "*?") {
+		like = "%" + like + "%"
+	}
+	return like
+}
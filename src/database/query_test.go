@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/google/uuid"
+)
+
+// pagingFixture is a library whose snippets tie on every sort key, with the uses and group positions they were given
+type pagingFixture struct {
+	db        *SQLiteHandler
+	uses      map[uuid.UUID]int64
+	positions map[uuid.UUID]int64
+}
+
+func newPagingFixture(t *testing.T) pagingFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := pagingFixture{
+		db:        newTestHandler(t, filepath.Join(t.TempDir(), "csnip.db")),
+		uses:      map[uuid.UUID]int64{},
+		positions: map[uuid.UUID]int64{},
+	}
+
+	var snippets []models.CodeSnippet
+	for i, s := range []struct{ name, language string }{
+		{"alpha", "go"}, {"Alpha", "go"}, {"beta", "bash"}, {"beta", "go"},
+		{"BETA", "bash"}, {"gamma", "python"}, {"alpha", "go"},
+	} {
+		snippet, err := f.db.AddNewSnippet(ctx, models.CodeSnippet{Name: s.name, Code: fmt.Sprintf("echo %d", i), Language: s.language})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snippets = append(snippets, snippet)
+	}
+	// a new version moves the snippet to the front of the date order and leaves a superseded row behind
+	if _, err := f.db.UpdateSnippet(ctx, snippets[1].Uuid, models.CodeSnippet{Code: "echo changed"}); err != nil {
+		t.Fatal(err)
+	}
+	// trashed snippets are not listed
+	if err := f.db.DeleteSnippetByUUID(ctx, snippets[5].Uuid); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range map[int]int64{0: 2, 2: 2, 3: 1} {
+		for range n {
+			if err := f.db.RecordSnippetUse(ctx, snippets[i].Uuid); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f.uses[snippets[i].Uuid] = n
+	}
+
+	if _, err := f.db.CreateGroup(ctx, "g", ""); err != nil {
+		t.Fatal(err)
+	}
+	for position, i := range []int{4, 1, 6, 0} {
+		if err := f.db.AddSnippetToGroup(ctx, "g", snippets[i].Uuid); err != nil {
+			t.Fatal(err)
+		}
+		f.positions[snippets[i].Uuid] = int64(position)
+	}
+	return f
+}
+
+// sortValue is the value filter sorts snippet by, text is compared ignoring case as the queries do
+func (f pagingFixture) sortValue(filter SnippetFilter, snippet models.CodeSnippet) (text string, number int64) {
+	switch filter.Sort {
+	case SortByName:
+		return strings.ToLower(snippet.Name), 0
+	case SortByLanguage:
+		return strings.ToLower(snippet.Language), 0
+	case SortByUsage:
+		return "", f.uses[snippet.Uuid]
+	case SortDefault:
+		if filter.Group != "" {
+			return "", f.positions[snippet.Uuid]
+		}
+	}
+	return "", snippet.ID
+}
+
+// checkOrder checks snippets are sorted by filter, with ties broken by id in the same direction
+func (f pagingFixture) checkOrder(t *testing.T, filter SnippetFilter, snippets []models.CodeSnippet) {
+	t.Helper()
+	for i := 1; i < len(snippets); i++ {
+		prevText, prevNumber := f.sortValue(filter, snippets[i-1])
+		text, number := f.sortValue(filter, snippets[i])
+		cmp := strings.Compare(prevText, text)
+		if cmp == 0 {
+			cmp = compareInt64(prevNumber, number)
+		}
+		if cmp == 0 {
+			cmp = compareInt64(snippets[i-1].ID, snippets[i].ID)
+		}
+		if filter.descending() {
+			cmp = -cmp
+		}
+		if cmp >= 0 {
+			t.Errorf("%s (id %d) is listed before %s (id %d)", snippets[i-1].Name, snippets[i-1].ID, snippets[i].Name, snippets[i].ID)
+		}
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// pager is the paging method shared by SQLiteHandler and LayeredHandler
+type pager interface {
+	QuerySnippetsPage(ctx context.Context, filter SnippetFilter) (SnippetPage, error)
+}
+
+// walkPages follows NextCursor from the first page to the last, checking each page's total and offset
+func walkPages(t *testing.T, db pager, filter SnippetFilter, total int) []models.CodeSnippet {
+	t.Helper()
+	var seen []models.CodeSnippet
+	for pages := 0; ; pages++ {
+		if pages > total+1 {
+			t.Fatalf("still paging after %d pages of %d snippets", pages, total)
+		}
+		page, err := db.QuerySnippetsPage(context.Background(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != int64(total) {
+			t.Errorf("page %d has total %d, want %d", pages, page.Total, total)
+		}
+		if page.Offset != int64(len(seen)) {
+			t.Errorf("page %d has offset %d, want %d", pages, page.Offset, len(seen))
+		}
+		if want := min(filter.Limit, int64(total-len(seen))); int64(len(page.Snippets)) != want {
+			t.Errorf("page %d has %d snippets, want %d", pages, len(page.Snippets), want)
+		}
+		seen = append(seen, page.Snippets...)
+		if page.NextCursor == "" {
+			return seen
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// checkSameSnippets checks got lists the same snippets as want in the same order, each once
+func checkSameSnippets(t *testing.T, got []models.CodeSnippet, want []models.CodeSnippet) {
+	t.Helper()
+	listed := map[uuid.UUID]bool{}
+	for _, snippet := range got {
+		if listed[snippet.Uuid] {
+			t.Errorf("%s is listed more than once", snippet.Uuid)
+		}
+		listed[snippet.Uuid] = true
+	}
+	if len(got) != len(want) {
+		t.Fatalf("listed %d snippets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Uuid != want[i].Uuid || got[i].Library != want[i].Library {
+			t.Errorf("snippet %d is %s in %q, want %s in %q", i, got[i].Uuid, got[i].Library, want[i].Uuid, want[i].Library)
+		}
+	}
+}
+
+// pagingFilters are every sort order in both directions, and the group order
+var pagingFilters = []struct {
+	name   string
+	filter SnippetFilter
+}{
+	{"default", SnippetFilter{}},
+	{"date", SnippetFilter{Sort: SortByDate}},
+	{"date reversed", SnippetFilter{Sort: SortByDate, Reverse: true}},
+	{"name", SnippetFilter{Sort: SortByName}},
+	{"name reversed", SnippetFilter{Sort: SortByName, Reverse: true}},
+	{"language", SnippetFilter{Sort: SortByLanguage}},
+	{"language reversed", SnippetFilter{Sort: SortByLanguage, Reverse: true}},
+	{"usage", SnippetFilter{Sort: SortByUsage}},
+	{"usage reversed", SnippetFilter{Sort: SortByUsage, Reverse: true}},
+	{"group", SnippetFilter{Group: "g"}},
+	{"group reversed", SnippetFilter{Group: "g", Reverse: true}},
+	{"group by name", SnippetFilter{Group: "g", Sort: SortByName}},
+}
+
+// TestQuerySnippetsPageCursor walks every page of every sort order with cursors and offsets, and checks
+// no snippet is repeated or skipped
+func TestQuerySnippetsPageCursor(t *testing.T) {
+	ctx := context.Background()
+	f := newPagingFixture(t)
+
+	for _, tt := range pagingFilters {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := f.db.QuerySnippets(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 6
+			if tt.filter.Group != "" {
+				want = 4
+			}
+			if len(all) != want {
+				t.Fatalf("listed %d snippets, want %d", len(all), want)
+			}
+			f.checkOrder(t, tt.filter, all)
+
+			for limit := int64(1); limit <= int64(len(all))+1; limit++ {
+				filter := tt.filter
+				filter.Limit = limit
+				checkSameSnippets(t, walkPages(t, f.db, filter, len(all)), all)
+
+				var byOffset []models.CodeSnippet
+				for filter.Offset = 0; filter.Offset < int64(len(all)); filter.Offset += limit {
+					page, err := f.db.QuerySnippetsPage(ctx, filter)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if page.Offset != filter.Offset {
+						t.Errorf("page has offset %d, want %d", page.Offset, filter.Offset)
+					}
+					byOffset = append(byOffset, page.Snippets...)
+				}
+				checkSameSnippets(t, byOffset, all)
+			}
+		})
+	}
+}
+
+// TestQuerySnippetsPageCursorAfterAdd checks a cursor carries on from where it was when a snippet is added
+// before it, and counts the new snippet in the offset
+func TestQuerySnippetsPageCursorAfterAdd(t *testing.T) {
+	ctx := context.Background()
+	f := newPagingFixture(t)
+
+	all, err := f.db.QuerySnippets(ctx, SnippetFilter{Sort: SortByName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := f.db.QuerySnippetsPage(ctx, SnippetFilter{Sort: SortByName, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.db.AddNewSnippet(ctx, models.CodeSnippet{Name: "Aardvark", Code: "echo new", Language: "go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rest, err := f.db.QuerySnippetsPage(ctx, SnippetFilter{Sort: SortByName, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Offset != 4 {
+		t.Errorf("offset after the cursor is %d, want 4", rest.Offset)
+	}
+	checkSameSnippets(t, append(first.Snippets, rest.Snippets...), all)
+}
+
+func TestQuerySnippetsPageInvalidCursor(t *testing.T) {
+	ctx := context.Background()
+	f := newPagingFixture(t)
+
+	page, err := f.db.QuerySnippetsPage(ctx, SnippetFilter{Sort: SortByName, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter SnippetFilter
+	}{
+		{"other sort order", SnippetFilter{Sort: SortByDate, Cursor: page.NextCursor}},
+		{"reversed", SnippetFilter{Sort: SortByName, Reverse: true, Cursor: page.NextCursor}},
+		{"not base64", SnippetFilter{Sort: SortByName, Cursor: "not a cursor!"}},
+		{"not json", SnippetFilter{Sort: SortByName, Cursor: "bm90IGpzb24"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.db.QuerySnippetsPage(ctx, tt.filter); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database/sqlite"
+)
+
+// columns of snippets_fts that fragments are taken from, in the order they are returned
+var searchFragmentColumns = []int{0, 1, 2, 3}
+
+// searchColumns are selected by both search queries, followed by the rank and any fragments
+const searchColumns = `s.id, s.uuid, s.name, s.code, s.language, s.description, s.source, s.date_added, s.version, s.superseded_by, s.tags, s.deleted_at`
+
+// Search returns the latest version of snippets matching query, best match first.
+// Each whitespace separated term in query is matched as a prefix against the name, description, tags and code.
+// Without the full text index each term is matched as a substring instead.
+func (s *SQLiteHandler) Search(ctx context.Context, query string, opts SearchOptions) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+
+	match := buildMatchExpression(query)
+	if match == "" {
+		return results, fmt.Errorf("search query must not be empty")
+	}
+
+	start, end := opts.HighlightStart, opts.HighlightEnd
+	if start == "" && end == "" {
+		start, end = "[", "]"
+	}
+
+	if !s.searchIndex {
+		return s.searchWithoutIndex(ctx, strings.Fields(query), opts, start, end)
+	}
+
+	// snippet() marks matches with a private marker, the caller's markers could already be in the text
+	markStart, markEnd := searchMarkers()
+	highlight := strings.NewReplacer(markStart, start, markEnd, end)
+
+	var fragments []string
+	var args []interface{}
+	for _, col := range searchFragmentColumns {
+		fragments = append(fragments, fmt.Sprintf("snippet(snippets_fts, %d, ?, ?, '...', 12)", col))
+		args = append(args, markStart, markEnd)
+	}
+
+	// weights for name, description, tags and code
+	sqlQuery := `SELECT ` + searchColumns + `,
+	bm25(snippets_fts, 10.0, 4.0, 6.0, 1.0) AS rank, ` + strings.Join(fragments, ", ") + `
+FROM snippets_fts
+JOIN snippet_details s ON s.id = snippets_fts.rowid
+WHERE snippets_fts MATCH ?
+AND s.superseded_by IS NULL
+AND s.deleted_at IS NULL`
+	args = append(args, match)
+	sqlQuery, args = searchLanguageAndLimit(sqlQuery, args, opts)
+
+	rows, err := s.database.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return results, fmt.Errorf("failed to search snippets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i sqlite.SnippetDetail
+		var rank float64
+		colFragments := make([]sql.NullString, len(searchFragmentColumns))
+		dest := searchRowDest(&i, &rank)
+		for idx := range colFragments {
+			dest = append(dest, &colFragments[idx])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return results, fmt.Errorf("failed to read search results: %w", err)
+		}
+
+		result := models.SearchResult{
+			CodeSnippet: convertSqliteSnippetDetailToCodeSnippet(i),
+			Rank:        rank,
+		}
+		// only keep fragments from the columns that actually matched
+		for _, f := range colFragments {
+			if fragment := getString(f); strings.Contains(fragment, markStart) {
+				result.Fragments = append(result.Fragments, highlight.Replace(fragment))
+			}
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return results, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	return results, nil
+}
+
+// buildMatchExpression quotes each term of the user query so punctuation in code is searched literally,
+// and marks it as a prefix so partial words still match.
+func buildMatchExpression(query string) string {
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchMarkers returns a pair of highlight markers for one search. They are made of private use characters
+// around a random number, so they will not be found in any snippet.
+func searchMarkers() (string, string) {
+	id := fmt.Sprintf("%016x", rand.Uint64())
+	return "\uE000" + id + "\uE001", "\uE001" + id + "\uE000"
+}
+
+// searchLanguageAndLimit adds the language filter, ordering and limit shared by both search queries
+func searchLanguageAndLimit(sqlQuery string, args []interface{}, opts SearchOptions) (string, []interface{}) {
+	if langs := languageVariants(opts.Language); opts.Language != "" {
+		sqlQuery += "\nAND LOWER(s.language) IN (" + placeholders(len(langs), "LOWER(?)") + ")"
+		for _, lang := range langs {
+			args = append(args, lang)
+		}
+	}
+
+	sqlQuery += "\nORDER BY rank"
+	if opts.Limit > 0 {
+		sqlQuery += "\nLIMIT ?"
+		args = append(args, opts.Limit)
+	}
+	return sqlQuery, args
+}
+
+// searchRowDest are the scan destinations for searchColumns and the rank
+func searchRowDest(i *sqlite.SnippetDetail, rank *float64) []interface{} {
+	return []interface{}{
+		&i.ID,
+		&i.Uuid,
+		&i.Name,
+		&i.Code,
+		&i.Language,
+		&i.Description,
+		&i.Source,
+		&i.DateAdded,
+		&i.Version,
+		&i.SupersededBy,
+		&i.Tags,
+		&i.DeletedAt,
+		rank,
+	}
+}
+
+// searchWithoutIndex is Search for sqlite built without FTS5. Every term must appear in the name, description,
+// tags or code, and matches are scored with the same column weights as the index. The rank is negated so the
+// best match sorts first, as it does with bm25.
+func (s *SQLiteHandler) searchWithoutIndex(ctx context.Context, terms []string, opts SearchOptions, start string, end string) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+
+	var conditions, scores []string
+	var scoreArgs, conditionArgs []interface{}
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		conditions = append(conditions, `(COALESCE(s.name, '') LIKE ? ESCAPE '\' OR COALESCE(s.description, '') LIKE ? ESCAPE '\' OR COALESCE(s.tags, '') LIKE ? ESCAPE '\' OR COALESCE(s.code, '') LIKE ? ESCAPE '\')`)
+		scores = append(scores, `10.0 * (COALESCE(s.name, '') LIKE ? ESCAPE '\') + 4.0 * (COALESCE(s.description, '') LIKE ? ESCAPE '\') + 6.0 * (COALESCE(s.tags, '') LIKE ? ESCAPE '\') + 1.0 * (COALESCE(s.code, '') LIKE ? ESCAPE '\')`)
+		for range 4 {
+			scoreArgs = append(scoreArgs, pattern)
+			conditionArgs = append(conditionArgs, pattern)
+		}
+	}
+
+	sqlQuery := `SELECT ` + searchColumns + `,
+	-(` + strings.Join(scores, " + ") + `) AS rank
+FROM snippet_details s
+WHERE s.superseded_by IS NULL
+AND s.deleted_at IS NULL
+AND ` + strings.Join(conditions, "\nAND ")
+	sqlQuery, args := searchLanguageAndLimit(sqlQuery, append(scoreArgs, conditionArgs...), opts)
+
+	rows, err := s.database.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return results, fmt.Errorf("failed to search snippets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i sqlite.SnippetDetail
+		var rank float64
+		if err := rows.Scan(searchRowDest(&i, &rank)...); err != nil {
+			return results, fmt.Errorf("failed to read search results: %w", err)
+		}
+
+		result := models.SearchResult{
+			CodeSnippet: convertSqliteSnippetDetailToCodeSnippet(i),
+			Rank:        rank,
+		}
+		for _, text := range []string{getString(i.Name), getString(i.Description), getString(i.Tags), i.Code} {
+			if fragment, ok := searchFragment(text, terms, start, end); ok {
+				result.Fragments = append(result.Fragments, fragment)
+			}
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return results, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	return results, nil
+}
+
+// likeEscaper escapes the LIKE wildcards so terms are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchFragmentContext is how many bytes of text a fragment keeps before the first match, twice as many follow it
+const searchFragmentContext = 40
+
+// searchFragment cuts the text around the first term it contains and wraps every term in the cut between start and
+// end, like snippet() does for the index. ok is false when text contains none of the terms.
+func searchFragment(text string, terms []string, start string, end string) (string, bool) {
+	// matching is case insensitive where lower casing leaves the byte offsets alone, which covers ASCII
+	folded := strings.ToLower(text)
+	if len(folded) != len(text) {
+		folded = text
+	}
+	lowerTerms := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.ToLower(term); term != "" {
+			lowerTerms = append(lowerTerms, term)
+		}
+	}
+
+	first := -1
+	for _, term := range lowerTerms {
+		if idx := strings.Index(folded, term); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from, to := max(0, first-searchFragmentContext), min(len(text), first+2*searchFragmentContext)
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("...")
+	}
+	for pos := from; pos < to; {
+		matched := ""
+		for _, term := range lowerTerms {
+			if strings.HasPrefix(folded[pos:], term) && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched == "" {
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			b.WriteString(text[pos : pos+size])
+			pos += size
+			continue
+		}
+		b.WriteString(start + text[pos:pos+len(matched)] + end)
+		pos += len(matched)
+	}
+	if to < len(text) {
+		b.WriteString("...")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// searchIndexTriggers keep snippets_fts in step with the latest, untrashed version of each snippet.
+// The rowid of each entry is the id of the snippet row it was taken from.
+var searchIndexTriggers = map[string]string{
+	// new snippets and new versions are always the latest version when inserted, their tags follow
+	"snippets_fts_insert": `CREATE TRIGGER snippets_fts_insert
+AFTER INSERT ON snippets
+FOR EACH ROW
+WHEN NEW.superseded_by IS NULL
+BEGIN
+    INSERT INTO snippets_fts (rowid, name, description, tags, code)
+    VALUES (NEW.id, NEW.name, NEW.description, NULL, NEW.code);
+END`,
+	// re-index on update so superseded and trashed versions drop out of the index
+	"snippets_fts_update": `CREATE TRIGGER snippets_fts_update
+AFTER UPDATE ON snippets
+FOR EACH ROW
+BEGIN
+    DELETE FROM snippets_fts WHERE rowid = OLD.id;
+    INSERT INTO snippets_fts (rowid, name, description, tags, code)
+    SELECT NEW.id, NEW.name, NEW.description, d.tags, NEW.code
+    FROM snippet_details d
+    WHERE d.id = NEW.id AND NEW.superseded_by IS NULL AND NEW.deleted_at IS NULL;
+END`,
+	"snippets_fts_delete": `CREATE TRIGGER snippets_fts_delete
+AFTER DELETE ON snippets
+FOR EACH ROW
+BEGIN
+    DELETE FROM snippets_fts WHERE rowid = OLD.id;
+END`,
+	"snippets_fts_tags_insert": `CREATE TRIGGER snippets_fts_tags_insert
+AFTER INSERT ON snippet_tags
+FOR EACH ROW
+BEGIN
+    UPDATE snippets_fts SET tags = (SELECT tags FROM snippet_details WHERE id = NEW.snippet_id)
+    WHERE rowid = NEW.snippet_id;
+END`,
+	"snippets_fts_tags_delete": `CREATE TRIGGER snippets_fts_tags_delete
+AFTER DELETE ON snippet_tags
+FOR EACH ROW
+BEGIN
+    UPDATE snippets_fts SET tags = (SELECT tags FROM snippet_details WHERE id = OLD.snippet_id)
+    WHERE rowid = OLD.snippet_id;
+END`,
+}
+
+// fts5Available reports whether the sqlite library was compiled with FTS5, which go-sqlite3 only does with the
+// sqlite_fts5 build tag
+func fts5Available(db *sql.DB) (bool, error) {
+	var fts5 bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return false, fmt.Errorf("unable to check for FTS5 support: %w", err)
+	}
+	return fts5, nil
+}
+
+// searchIndexState reports whether snippets_fts and every one of its triggers exist as ensureSearchIndex creates
+// them, and how many of those triggers exist at all
+func searchIndexState(q execQuerier) (current bool, triggers int, err error) {
+	var tables int
+	if err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'snippets_fts'").Scan(&tables); err != nil {
+		return false, 0, fmt.Errorf("unable to inspect search index: %w", err)
+	}
+	current = tables == 1
+	for name, trigger := range searchIndexTriggers {
+		var stored string
+		err := q.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = ?", name).Scan(&stored)
+		if err == sql.ErrNoRows {
+			current = false
+			continue
+		}
+		if err != nil {
+			return false, 0, fmt.Errorf("unable to inspect search index: %w", err)
+		}
+		triggers++
+		current = current && strings.TrimSpace(stored) == trigger
+	}
+	return current, triggers, nil
+}
+
+// searchIndexNeedsWork reports whether the index has to be rebuilt with FTS5, or its triggers dropped without it
+func searchIndexNeedsWork(q execQuerier, fts5 bool) (bool, error) {
+	current, triggers, err := searchIndexState(q)
+	if err != nil {
+		return false, err
+	}
+	if fts5 {
+		return !current, nil
+	}
+	return triggers > 0, nil
+}
+
+// ensureSearchIndex prepares the full text index after the schema has been migrated and reports whether Search can
+// use it. With FTS5 a missing or outdated index is rebuilt from the snippets. Without FTS5 the triggers an earlier
+// build created are dropped, as every write would fail on them, and the index is rebuilt when FTS5 is next available.
+func ensureSearchIndex(db *sql.DB) (bool, error) {
+	fts5, err := fts5Available(db)
+	if err != nil {
+		return false, err
+	}
+
+	needed, err := searchIndexNeedsWork(db, fts5)
+	if err != nil || !needed {
+		return fts5, err
+	}
+
+	// the index is checked again once the write lock is held, another process may have just rebuilt it
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction for search index: %w", err)
+	}
+	defer tx.Rollback()
+
+	if needed, err = searchIndexNeedsWork(tx, fts5); err != nil || !needed {
+		return fts5, err
+	}
+
+	for name := range searchIndexTriggers {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+			return false, fmt.Errorf("failed to remove search index trigger %s: %w", name, err)
+		}
+	}
+
+	if fts5 {
+		statements := []string{
+			"CREATE VIRTUAL TABLE IF NOT EXISTS snippets_fts USING fts5(name, description, tags, code)",
+			"DELETE FROM snippets_fts",
+			`INSERT INTO snippets_fts (rowid, name, description, tags, code)
+SELECT id, name, description, tags, code FROM snippet_details WHERE superseded_by IS NULL AND deleted_at IS NULL`,
+		}
+		for _, trigger := range searchIndexTriggers {
+			statements = append(statements, trigger)
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return false, fmt.Errorf("failed to build search index: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit search index: %w", err)
+	}
+	return fts5, nil
+}
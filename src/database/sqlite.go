@@ -5,7 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Ryan-Har/csnip/common"
 	"github.com/Ryan-Har/csnip/common/models"
@@ -14,57 +21,166 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// SQLiteHandler is safe for use from many goroutines. Reads run concurrently on a pool of connections while
+// writes are serialised by writeMutex, and other processes are waited on for up to busyTimeout.
 type SQLiteHandler struct {
 	database   *sql.DB
 	queries    *sqlite.Queries
 	version    int32 //version of database schema
-	writeMutex *sync.Mutex
+	writeMutex sync.Mutex
+	// searchIndex is set when sqlite has FTS5 and snippets_fts is kept up to date, Search scans the snippets otherwise
+	searchIndex bool
 }
 
-func NewSQLiteHandler() (DatabaseInteractions, error) {
+// busyTimeout is how long a connection waits for another process to finish writing before giving up with database is locked
+const busyTimeout = 5 * time.Second
+
+// NewSQLiteHandler opens the database at dbLoc, creating it and any parent directories if they do not exist
+func NewSQLiteHandler(dbLoc string) (DatabaseInteractions, error) {
+	var dbHandler DatabaseInteractions
+	if err := os.MkdirAll(filepath.Dir(dbLoc), 0o755); err != nil {
+		return dbHandler, fmt.Errorf("unable to create database directory: %w", err)
+	}
+
+	db, err := openSQLiteDB(sqliteDSN(dbLoc), isMemoryDB(dbLoc))
+	if err != nil {
+		return dbHandler, err
+	}
+
+	version, err := migrateSQLiteDB(db, dbLoc)
+	if err != nil {
+		db.Close()
+		return dbHandler, err
+	}
+
+	searchIndex, err := ensureSearchIndex(db)
+	if err != nil {
+		db.Close()
+		return dbHandler, err
+	}
+
+	dbHandler = &SQLiteHandler{
+		database:    db,
+		queries:     sqlite.New(db),
+		version:     version,
+		searchIndex: searchIndex,
+	}
+	return dbHandler, nil
+}
+
+// NewReadOnlySQLiteHandler opens the existing database at dbLoc for reading only, such as a library shared by a team.
+// Nothing is written to it, so it is never migrated or backed up and its schema must match this version of csnip.
+func NewReadOnlySQLiteHandler(dbLoc string) (DatabaseInteractions, error) {
 	var dbHandler DatabaseInteractions
-	dbLoc := "./my.db"
-	db, err := openSQLiteDB(dbLoc)
+	db, err := openSQLiteDB(readOnlySQLiteDSN(dbLoc), false)
 	if err != nil {
 		return dbHandler, err
 	}
 
+	version, err := checkSchemaVersion(db)
+	if err != nil {
+		db.Close()
+		return dbHandler, err
+	}
+
+	// the index can only be used as it is, it is rebuilt when the library is next opened for writing
+	searchIndex, err := fts5Available(db)
+	if err == nil && searchIndex {
+		searchIndex, _, err = searchIndexState(db)
+	}
+	if err != nil {
+		db.Close()
+		return dbHandler, err
+	}
+
 	dbHandler = &SQLiteHandler{
-		database:   db,
-		queries:    sqlite.New(db),
-		version:    1,
-		writeMutex: &sync.Mutex{},
+		database:    db,
+		queries:     sqlite.New(db),
+		version:     version,
+		searchIndex: searchIndex,
 	}
 	return dbHandler, nil
 }
 
-func openSQLiteDB(dbLoc string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dbLoc)
+func openSQLiteDB(dsn string, memory bool) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	// every connection in an in memory pool would get its own empty database
+	if memory {
+		db.SetMaxOpenConns(1)
+	} else {
+		conns := max(4, runtime.NumCPU())
+		db.SetMaxOpenConns(conns)
+		db.SetMaxIdleConns(conns)
+	}
+
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
-		// Handle error setting foreign_keys
-		return nil, fmt.Errorf("unable to enforce foreign keys in db: %v", err.Error())
+	var foreignKeys bool
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || !foreignKeys {
+		db.Close()
+		return nil, fmt.Errorf("unable to enforce foreign keys in db: %v", err)
 	}
 	return db, nil
 }
 
-func (s SQLiteHandler) PopulateHelloWorldSnippets() error {
+// sqliteDSN adds the connection settings to dbLoc, they are applied to each connection the pool opens.
+// WAL lets readers carry on while a write is in progress, and write transactions take the write lock as soon as they
+// begin so two writers wait on each other instead of one failing when it tries to upgrade a read lock.
+func sqliteDSN(dbLoc string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	params.Set("_txlock", "immediate")
+	if !isMemoryDB(dbLoc) {
+		params.Set("_journal_mode", "WAL")
+		params.Set("_synchronous", "NORMAL")
+	}
+
+	sep := "?"
+	if strings.Contains(dbLoc, "?") {
+		sep = "&"
+	}
+	return dbLoc + sep + params.Encode()
+}
+
+// readOnlySQLiteDSN opens dbLoc with mode=ro, which sqlite only reads from a file: URI.
+// The journal mode is left as it is, changing it would write to the database.
+func readOnlySQLiteDSN(dbLoc string) string {
+	params := url.Values{}
+	params.Set("mode", "ro")
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	return "file:" + sqliteURIEscaper.Replace(dbLoc) + "?" + params.Encode()
+}
+
+// sqliteURIEscaper escapes the characters of a path that have a meaning in a sqlite file: URI
+var sqliteURIEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+func isMemoryDB(dbLoc string) bool {
+	return dbLoc == "" || strings.HasPrefix(dbLoc, ":memory:") || strings.Contains(dbLoc, "mode=memory")
+}
+
+// Close closes the underlying database, it should be called once the handler is no longer needed
+func (s *SQLiteHandler) Close() error {
+	return s.database.Close()
+}
+
+func (s *SQLiteHandler) PopulateHelloWorldSnippets(ctx context.Context) error {
 	examples := common.GetHelloWorldExamples()
 	for lang, code := range examples {
-		err := s.AddNewSnippet(models.CodeSnippet{
+		_, err := s.AddNewSnippet(ctx, models.CodeSnippet{
 			Name:        "Hello World Example in " + lang,
 			Code:        code,
 			Language:    lang,
-			Tags:        "example,generated",
+			Tags:        []string{"example", "generated"},
 			Description: "Hello World Example in " + lang,
 			Source:      "generated",
 		})
@@ -75,53 +191,125 @@ func (s SQLiteHandler) PopulateHelloWorldSnippets() error {
 	return nil
 }
 
-func (s SQLiteHandler) AddNewSnippet(m models.CodeSnippet) error {
+// AddNewSnippet stores m as the first version of a new snippet and returns it as stored
+func (s *SQLiteHandler) AddNewSnippet(ctx context.Context, m models.CodeSnippet) (models.CodeSnippet, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
 	m.Uuid = uuid.New()
 	m.Version = 1
+	if lang, ok := common.CanonicalLanguage(m.Language); ok {
+		m.Language = lang
+	}
 
 	createParams := codeSnippetModelToDbCreateSnippetParams(m)
-	_, err := s.queries.CreateSnippet(context.Background(), createParams)
-	return err
+
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CodeSnippet{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	q := s.queries.WithTx(tx)
+
+	createdSnippet, err := q.CreateSnippet(ctx, createParams)
+	if err != nil {
+		tx.Rollback()
+		return models.CodeSnippet{}, fmt.Errorf("failed to insert snippet: %w", err)
+	}
+
+	if err := addSnippetTags(ctx, q, createdSnippet.ID, m.Tags); err != nil {
+		tx.Rollback()
+		return models.CodeSnippet{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.CodeSnippet{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetSnippetByUUID(ctx, m.Uuid)
 }
 
 // updates the uuid with the changedSnippet
-func (s SQLiteHandler) UpdateSnippet(u uuid.UUID, changedSnippet models.CodeSnippet) (models.CodeSnippet, error) {
+func (s *SQLiteHandler) UpdateSnippet(ctx context.Context, u uuid.UUID, changedSnippet models.CodeSnippet) (models.CodeSnippet, error) {
+	return s.createSnippetVersion(ctx, u, func(q *sqlite.Queries, latest sqlite.SnippetDetail) (models.CodeSnippet, error) {
+		return normaliseCodeSnippetStruct(changedSnippet, convertSqliteSnippetDetailToCodeSnippet(latest)), nil
+	})
+}
+
+// RevertSnippet creates a new latest version of the snippet copying an older version, so history is never rewritten
+func (s *SQLiteHandler) RevertSnippet(ctx context.Context, u uuid.UUID, version int64) (models.CodeSnippet, error) {
+	return s.createSnippetVersion(ctx, u, func(q *sqlite.Queries, latest sqlite.SnippetDetail) (models.CodeSnippet, error) {
+		params := sqlite.GetSnippetVersionParams{
+			Uuid:    u.String(),
+			Version: version,
+		}
+		historic, err := q.GetSnippetVersion(ctx, params)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.CodeSnippet{}, ErrVersionNotFound
+			}
+			return models.CodeSnippet{}, fmt.Errorf("failed to retrieve snippet version: %w", err)
+		}
+
+		snippetToRevert := convertSqliteSnippetDetailToCodeSnippet(historic)
+		snippetToRevert.Version = latest.Version + 1
+		return snippetToRevert, nil
+	})
+}
+
+// createSnippetVersion stores the snippet returned by next as the newest version and marks the previous latest version
+// as superseded by it. The latest version is read in the same write transaction, so concurrent updates each build on
+// the one before and the superseded_by chain never forks.
+func (s *SQLiteHandler) createSnippetVersion(ctx context.Context, u uuid.UUID, next func(q *sqlite.Queries, latest sqlite.SnippetDetail) (models.CodeSnippet, error)) (models.CodeSnippet, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
 	//initialise return snippet
 	var returnSnippet models.CodeSnippet
 
-	//get last change as oldSnippet
-	oldSnippet, err := s.queries.GetSnippetByUUID(context.Background(), u.String())
+	//begin transaction
+	tx, err := s.database.BeginTx(ctx, nil)
 	if err != nil {
-		return returnSnippet, err
+		return returnSnippet, fmt.Errorf("failed to start transaction: %w", err)
 	}
 
-	oldCodeSnippet := convertSqliteSnippetToCodeSnippet(oldSnippet)
-	snippetToUpdate := normaliseCodeSnippetStruct(changedSnippet, oldCodeSnippet)
-	createParams := codeSnippetModelToDbCreateSnippetParams(snippetToUpdate)
+	q := s.queries.WithTx(tx)
 
-	//begin transaction
-	tx, err := s.database.BeginTx(context.Background(), nil)
+	previous, err := q.GetSnippetByUUID(ctx, u.String())
 	if err != nil {
-		return returnSnippet, fmt.Errorf("failed to start transaction: %w", err)
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return returnSnippet, ErrNoSnippetsFound
+		}
+		return returnSnippet, fmt.Errorf("failed to retrieve snippet: %w", err)
 	}
 
-	q := s.queries.WithTx(tx)
+	snippet, err := next(q, previous)
+	if err != nil {
+		tx.Rollback()
+		return returnSnippet, err
+	}
 
-	createdSnippet, err := q.CreateSnippet(context.Background(), createParams)
+	createdSnippet, err := q.CreateSnippet(ctx, codeSnippetModelToDbCreateSnippetParams(snippet))
 	if err != nil {
 		tx.Rollback()
 		return returnSnippet, fmt.Errorf("failed to insert snippet: %w", err)
 	}
 
+	// tags belong to each version, so the new version needs its own copy
+	if err := addSnippetTags(ctx, q, createdSnippet.ID, snippet.Tags); err != nil {
+		tx.Rollback()
+		return returnSnippet, err
+	}
+
 	supersededParams := sqlite.MarkSnippetSupersededParams{
 		SupersededBy: sql.NullInt64{
 			Int64: createdSnippet.ID,
 			Valid: true,
 		},
-		ID: oldSnippet.ID,
+		ID: previous.ID,
 	}
 
-	err = q.MarkSnippetSuperseded(context.Background(), supersededParams)
+	err = q.MarkSnippetSuperseded(ctx, supersededParams)
 	if err != nil {
 		tx.Rollback()
 		return returnSnippet, fmt.Errorf("failed to mark snippet superceded: %w", err)
@@ -131,116 +319,96 @@ func (s SQLiteHandler) UpdateSnippet(u uuid.UUID, changedSnippet models.CodeSnip
 		return returnSnippet, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return convertSqliteSnippetToCodeSnippet(createdSnippet), nil
-
+	returnSnippet = convertSqliteSnippetToCodeSnippet(createdSnippet)
+	returnSnippet.Tags = common.NormaliseTags(snippet.Tags)
+	return returnSnippet, nil
 }
 
 // GetSnippets returns a list of snippets.
 // item is paginated for efficiency, inputs are the page number needed and the limit for response.
-func (s SQLiteHandler) GetSnippets(page int64, limit int64) ([]models.CodeSnippet, error) {
-	var responseSnippets []models.CodeSnippet
-	offset := (page - 1) * limit
-
-	pageParams := sqlite.ListSnippetsByPageParams{
-		Offset: offset,
+func (s *SQLiteHandler) GetSnippets(ctx context.Context, page int64, limit int64) ([]models.CodeSnippet, error) {
+	return s.QuerySnippets(ctx, SnippetFilter{
 		Limit:  limit,
-	}
-
-	dbSnippets, err := s.queries.ListSnippetsByPage(context.Background(), pageParams)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return responseSnippets, ErrNoSnippetsFound
-		}
-		return responseSnippets, fmt.Errorf("failed to retrieve snippets: %w", err)
-	}
-
-	for _, snippet := range dbSnippets {
-		responseSnippets = append(responseSnippets, convertSqliteSnippetToCodeSnippet(snippet))
-	}
-
-	return responseSnippets, nil
+		Offset: (page - 1) * limit,
+	})
 }
 
 // GetSnippetsByLanguage returns a list of snippets
-func (s SQLiteHandler) GetSnippetsByLanguage(lang string) ([]models.CodeSnippet, error) {
-	var responseSnippets []models.CodeSnippet
+func (s *SQLiteHandler) GetSnippetsByLanguage(ctx context.Context, lang string) ([]models.CodeSnippet, error) {
+	return s.QuerySnippets(ctx, SnippetFilter{Languages: []string{lang}})
+}
 
-	dbSnippets, err := s.queries.GetSnippetByLanguage(context.Background(), lang)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return responseSnippets, ErrNoSnippetsFound
-		}
-		return responseSnippets, fmt.Errorf("failed to retrieve snippets: %w", err)
-	}
+// GetSnippetsByTag returns a list of snippets tagged with tag, ignoring case
+func (s *SQLiteHandler) GetSnippetsByTag(ctx context.Context, tag string) ([]models.CodeSnippet, error) {
+	return s.GetSnippetsByTags(ctx, []string{tag}, TagMatchAny)
+}
 
-	for _, snippet := range dbSnippets {
-		responseSnippets = append(responseSnippets, convertSqliteSnippetToCodeSnippet(snippet))
+// GetSnippetsByTags returns a list of snippets tagged with any or all of the tags, depending on match
+func (s *SQLiteHandler) GetSnippetsByTags(ctx context.Context, tags []string, match TagMatch) ([]models.CodeSnippet, error) {
+	if len(common.NormaliseTags(tags)) == 0 {
+		return nil, nil
 	}
+	return s.QuerySnippets(ctx, SnippetFilter{Tags: tags, TagMatch: match})
+}
 
-	return responseSnippets, nil
+// GetSnippetsByLanguageAndTags returns a list of snippets where language matches and the snippet is tagged with any or all of the tags, depending on match
+func (s *SQLiteHandler) GetSnippetsByLanguageAndTags(ctx context.Context, lang string, tags []string, match TagMatch) ([]models.CodeSnippet, error) {
+	return s.QuerySnippets(ctx, SnippetFilter{Languages: []string{lang}, Tags: tags, TagMatch: match})
 }
 
-// GetSnippetsByTag returns a list of snippets where the tag string provided patially matches the list of tags in the database
-func (s SQLiteHandler) GetSnippetsByTag(tag string) ([]models.CodeSnippet, error) {
-	var responseSnippets []models.CodeSnippet
+// ListTags returns every tag used by the latest version of a snippet, most used first
+func (s *SQLiteHandler) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	var tags []models.TagCount
 
-	dbSnippets, err := s.queries.GetSnippetByTag(context.Background(), tag)
+	dbTags, err := s.queries.ListTags(ctx)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return responseSnippets, ErrNoSnippetsFound
-		}
-		return responseSnippets, fmt.Errorf("failed to retrieve snippets: %w", err)
+		return tags, fmt.Errorf("failed to retrieve tags: %w", err)
 	}
 
-	for _, snippet := range dbSnippets {
-		responseSnippets = append(responseSnippets, convertSqliteSnippetToCodeSnippet(snippet))
+	for _, t := range dbTags {
+		tags = append(tags, models.TagCount{
+			Name:  t.Name,
+			Count: t.UsageCount,
+		})
 	}
-
-	return responseSnippets, nil
+	return tags, nil
 }
 
-// GetSnippetsByLanguageAndTag returns a list of snippets where language matches and the tag string provided patially matches the list of tags in the database
-func (s SQLiteHandler) GetSnippetsByLanguageAndTag(lang string, tag string) ([]models.CodeSnippet, error) {
-	var responseSnippets []models.CodeSnippet
-
-	params := sqlite.GetSnippetByLanguageAndTagParams{
-		Language: lang,
-		INSTR:    tag,
-	}
-
-	dbSnippets, err := s.queries.GetSnippetByLanguageAndTag(context.Background(), params)
+// GetSnippetsByUUID returns a single snippet matching the UUID, along with the names of the groups it is in
+func (s *SQLiteHandler) GetSnippetByUUID(ctx context.Context, u uuid.UUID) (models.CodeSnippet, error) {
+	dbSnippet, err := s.queries.GetSnippetByUUID(ctx, u.String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return responseSnippets, ErrNoSnippetsFound
+			return models.CodeSnippet{}, ErrNoSnippetsFound
 		}
-		return responseSnippets, fmt.Errorf("failed to retrieve snippets: %w", err)
+		return models.CodeSnippet{}, fmt.Errorf("failed to retrieve snippets: %w", err)
 	}
 
-	for _, snippet := range dbSnippets {
-		responseSnippets = append(responseSnippets, convertSqliteSnippetToCodeSnippet(snippet))
+	snippet := convertSqliteSnippetDetailToCodeSnippet(dbSnippet)
+	snippet.Groups, err = s.queries.GetGroupNamesBySnippet(ctx, u.String())
+	if err != nil {
+		return snippet, fmt.Errorf("failed to retrieve groups for snippet: %w", err)
 	}
 
-	return responseSnippets, nil
+	return snippet, nil
 }
 
-// GetSnippetsByUUID returns a single snippet matching the UUID
-func (s SQLiteHandler) GetSnippetByUUID(u uuid.UUID) (models.CodeSnippet, error) {
-	dbSnippet, err := s.queries.GetSnippetByUUID(context.Background(), u.String())
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.CodeSnippet{}, ErrNoSnippetsFound
-		}
-		return models.CodeSnippet{}, fmt.Errorf("failed to retrieve snippets: %w", err)
-	}
+// RecordSnippetUse counts a use of the snippet, used to sort snippets by how often they are used
+func (s *SQLiteHandler) RecordSnippetUse(ctx context.Context, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
 
-	return convertSqliteSnippetToCodeSnippet(dbSnippet), nil
+	if err := s.queries.RecordSnippetUse(ctx, u.String()); err != nil {
+		return fmt.Errorf("failed to record snippet use: %w", err)
+	}
+	return nil
 }
 
 // GetSnippetHistoryByUUID returns a the snippet history
-func (s SQLiteHandler) GetSnippetHistoryByUUID(u uuid.UUID) ([]models.CodeSnippet, error) {
+func (s *SQLiteHandler) GetSnippetHistoryByUUID(ctx context.Context, u uuid.UUID) ([]models.CodeSnippet, error) {
 	var responseSnippets []models.CodeSnippet
 
-	dbSnippets, err := s.queries.GetSnippetVersions(context.Background(), u.String())
+	dbSnippets, err := s.queries.GetSnippetVersions(ctx, u.String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return responseSnippets, ErrNoSnippetsFound
@@ -249,18 +417,24 @@ func (s SQLiteHandler) GetSnippetHistoryByUUID(u uuid.UUID) ([]models.CodeSnippe
 	}
 
 	for _, snippet := range dbSnippets {
-		responseSnippets = append(responseSnippets, convertSqliteSnippetToCodeSnippet(snippet))
+		responseSnippets = append(responseSnippets, convertSqliteSnippetDetailToCodeSnippet(snippet))
 	}
 
 	return responseSnippets, nil
 }
 
-// DeleteSnippetByUUID returns a single snippet matching the UUID
-func (s SQLiteHandler) DeleteSnippetByUUID(u uuid.UUID) error {
-	err := s.queries.DeleteSnippetByUUID(context.Background(), u.String())
+// DeleteSnippetByUUID moves every version of the snippet to the trash, it can be brought back with RestoreSnippetByUUID
+func (s *SQLiteHandler) DeleteSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	trashed, err := s.queries.TrashSnippetByUUID(ctx, u.String())
 	if err != nil {
 		return fmt.Errorf("failed to delete snippet by uuid: %w", err)
 	}
+	if trashed == 0 {
+		return ErrNoSnippetsFound
+	}
 	return nil
 }
 
@@ -271,7 +445,6 @@ func codeSnippetModelToDbCreateSnippetParams(m models.CodeSnippet) sqlite.Create
 		Name:        toNullString(m.Name),
 		Code:        m.Code,
 		Language:    m.Language,
-		Tags:        toNullString(m.Tags),
 		Description: toNullString(m.Description),
 		Source:      toNullString(m.Source),
 		Version:     m.Version,
@@ -285,7 +458,6 @@ func sqliteSnippetToDbCreateSnippetParams(s sqlite.Snippet) sqlite.CreateSnippet
 		Name:        s.Name,
 		Code:        s.Code,
 		Language:    s.Language,
-		Tags:        s.Tags,
 		Description: s.Description,
 		Source:      s.Source,
 		Version:     s.Version,
@@ -306,13 +478,61 @@ func convertSqliteSnippetToCodeSnippet(s sqlite.Snippet) models.CodeSnippet {
 		Name:         getString(s.Name),
 		Code:         s.Code,
 		Language:     s.Language,
-		Tags:         getString(s.Tags),
 		Description:  getString(s.Description),
 		Source:       getString(s.Source),
 		DateAdded:    getTime(s.DateAdded),
 		Version:      s.Version,
 		SupersededBy: getInt64(s.SupersededBy),
+		DeletedAt:    getTimePtr(s.DeletedAt),
+	}
+}
+
+// Convert Snippet with tags (DB) -> CodeSnippet model
+func convertSqliteSnippetDetailToCodeSnippet(s sqlite.SnippetDetail) models.CodeSnippet {
+	m := convertSqliteSnippetToCodeSnippet(sqlite.Snippet{
+		ID:           s.ID,
+		Uuid:         s.Uuid,
+		Name:         s.Name,
+		Code:         s.Code,
+		Language:     s.Language,
+		Description:  s.Description,
+		Source:       s.Source,
+		DateAdded:    s.DateAdded,
+		Version:      s.Version,
+		SupersededBy: s.SupersededBy,
+		DeletedAt:    s.DeletedAt,
+	})
+	m.Tags = []string{}
+	if tags := getString(s.Tags); tags != "" {
+		m.Tags = strings.Split(tags, ",")
+	}
+	return m
+}
+
+// minTagMatches returns how many of the tags a snippet needs to match
+func minTagMatches(tags []string, match TagMatch) int64 {
+	if match == TagMatchAll {
+		return int64(len(tags))
+	}
+	return 1
+}
+
+// addSnippetTags links the tags to a snippet version, creating any tags that do not exist yet
+func addSnippetTags(ctx context.Context, q *sqlite.Queries, snippetID int64, tags []string) error {
+	for _, tag := range common.NormaliseTags(tags) {
+		if err := q.CreateTag(ctx, tag); err != nil {
+			return fmt.Errorf("failed to create tag %q: %w", tag, err)
+		}
+
+		params := sqlite.AddSnippetTagParams{
+			SnippetID: snippetID,
+			Name:      tag,
+		}
+		if err := q.AddSnippetTag(ctx, params); err != nil {
+			return fmt.Errorf("failed to tag snippet with %q: %w", tag, err)
+		}
 	}
+	return nil
 }
 
 // compares code snippets to ensure that any missing fields are retained from the old snippet
@@ -326,10 +546,14 @@ func normaliseCodeSnippetStruct(toUpdate models.CodeSnippet, old models.CodeSnip
 	if toUpdate.Code == "" {
 		toUpdate.Code = old.Code
 	}
-	if toUpdate.Language != old.Language {
+	if toUpdate.Language == "" {
 		toUpdate.Language = old.Language
 	}
-	if toUpdate.Tags == "" {
+	// snippets stored before languages were canonicalised are brought in line when updated
+	if lang, ok := common.CanonicalLanguage(toUpdate.Language); ok {
+		toUpdate.Language = lang
+	}
+	if len(toUpdate.Tags) == 0 {
 		toUpdate.Tags = old.Tags
 	}
 	if toUpdate.Description == "" {
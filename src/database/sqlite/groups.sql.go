@@ -10,29 +10,46 @@ import (
 	"database/sql"
 )
 
+const addGroupMember = `-- name: AddGroupMember :exec
+INSERT INTO group_members (
+    group_id, snippet_uuid, position
+) VALUES (
+    ?1, ?2, (SELECT COALESCE(MAX(position), 0) + 1 FROM group_members WHERE group_id = ?1)
+) ON CONFLICT (group_id, snippet_uuid) DO NOTHING
+`
+
+type AddGroupMemberParams struct {
+	GroupID     int64
+	SnippetUuid string
+}
+
+// Adds a snippet to the end of a group, does nothing if it is already a member
+func (q *Queries) AddGroupMember(ctx context.Context, arg AddGroupMemberParams) error {
+	_, err := q.db.ExecContext(ctx, addGroupMember, arg.GroupID, arg.SnippetUuid)
+	return err
+}
+
 const createGroup = `-- name: CreateGroup :one
 INSERT INTO groups (
-    group_name, description, uuid_list, date_added, date_updated
+    group_name, description, date_added, date_updated
 ) VALUES (
-    ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
-) RETURNING id, group_name, description, uuid_list, date_added, date_updated
+    ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+) RETURNING id, group_name, description, date_added, date_updated
 `
 
 type CreateGroupParams struct {
 	GroupName   string
 	Description sql.NullString
-	UuidList    string
 }
 
 // Creates a new group
 func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
-	row := q.db.QueryRowContext(ctx, createGroup, arg.GroupName, arg.Description, arg.UuidList)
+	row := q.db.QueryRowContext(ctx, createGroup, arg.GroupName, arg.Description)
 	var i Group
 	err := row.Scan(
 		&i.ID,
 		&i.GroupName,
 		&i.Description,
-		&i.UuidList,
 		&i.DateAdded,
 		&i.DateUpdated,
 	)
@@ -50,7 +67,7 @@ func (q *Queries) DeleteGroup(ctx context.Context, id int64) error {
 }
 
 const getAllGroups = `-- name: GetAllGroups :many
-SELECT id, group_name, description, uuid_list, date_added, date_updated FROM groups ORDER BY date_added DESC
+SELECT id, group_name, description, date_added, date_updated FROM groups ORDER BY date_added DESC
 `
 
 // Get all groups
@@ -67,7 +84,6 @@ func (q *Queries) GetAllGroups(ctx context.Context) ([]Group, error) {
 			&i.ID,
 			&i.GroupName,
 			&i.Description,
-			&i.UuidList,
 			&i.DateAdded,
 			&i.DateUpdated,
 		); err != nil {
@@ -85,7 +101,7 @@ func (q *Queries) GetAllGroups(ctx context.Context) ([]Group, error) {
 }
 
 const getGroupByID = `-- name: GetGroupByID :one
-SELECT id, group_name, description, uuid_list, date_added, date_updated FROM groups WHERE id = ?
+SELECT id, group_name, description, date_added, date_updated FROM groups WHERE id = ?
 `
 
 // Get a group by its ID
@@ -96,33 +112,183 @@ func (q *Queries) GetGroupByID(ctx context.Context, id int64) (Group, error) {
 		&i.ID,
 		&i.GroupName,
 		&i.Description,
-		&i.UuidList,
 		&i.DateAdded,
 		&i.DateUpdated,
 	)
 	return i, err
 }
 
+const getGroupByName = `-- name: GetGroupByName :one
+SELECT id, group_name, description, date_added, date_updated FROM groups WHERE LOWER(group_name) = LOWER(?)
+`
+
+// Get a group by its name, ignoring case
+func (q *Queries) GetGroupByName(ctx context.Context, lower string) (Group, error) {
+	row := q.db.QueryRowContext(ctx, getGroupByName, lower)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.GroupName,
+		&i.Description,
+		&i.DateAdded,
+		&i.DateUpdated,
+	)
+	return i, err
+}
+
+const getGroupNamesBySnippet = `-- name: GetGroupNamesBySnippet :many
+SELECT g.group_name FROM groups g
+JOIN group_members gm ON gm.group_id = g.id
+WHERE gm.snippet_uuid = ?
+ORDER BY g.group_name
+`
+
+// Get the names of the groups a snippet belongs to
+func (q *Queries) GetGroupNamesBySnippet(ctx context.Context, snippetUuid string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getGroupNamesBySnippet, snippetUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var group_name string
+		if err := rows.Scan(&group_name); err != nil {
+			return nil, err
+		}
+		items = append(items, group_name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupMembers = `-- name: ListGroupMembers :many
+SELECT snippet_uuid FROM group_members
+WHERE group_id = ?
+AND snippet_uuid NOT IN (SELECT uuid FROM snippets WHERE deleted_at IS NOT NULL)
+ORDER BY position
+`
+
+// Get the UUIDs of the snippets in a group that are not in the trash, in group order
+func (q *Queries) ListGroupMembers(ctx context.Context, groupID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listGroupMembers, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var snippet_uuid string
+		if err := rows.Scan(&snippet_uuid); err != nil {
+			return nil, err
+		}
+		items = append(items, snippet_uuid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSnippetGroupMembers = `-- name: ListSnippetGroupMembers :many
+SELECT group_id, snippet_uuid, position, date_added FROM group_members WHERE snippet_uuid = ?
+`
+
+// Get the group memberships of a snippet
+func (q *Queries) ListSnippetGroupMembers(ctx context.Context, snippetUuid string) ([]GroupMember, error) {
+	rows, err := q.db.QueryContext(ctx, listSnippetGroupMembers, snippetUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GroupMember
+	for rows.Next() {
+		var i GroupMember
+		if err := rows.Scan(
+			&i.GroupID,
+			&i.SnippetUuid,
+			&i.Position,
+			&i.DateAdded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeGroupMember = `-- name: RemoveGroupMember :execrows
+DELETE FROM group_members WHERE group_id = ? AND snippet_uuid = ?
+`
+
+type RemoveGroupMemberParams struct {
+	GroupID     int64
+	SnippetUuid string
+}
+
+// Removes a snippet from a group
+func (q *Queries) RemoveGroupMember(ctx context.Context, arg RemoveGroupMemberParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeGroupMember, arg.GroupID, arg.SnippetUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreGroupMember = `-- name: RestoreGroupMember :exec
+INSERT INTO group_members (
+    group_id, snippet_uuid, position, date_added
+) VALUES (
+    ?, ?, ?, ?
+) ON CONFLICT (group_id, snippet_uuid) DO NOTHING
+`
+
+type RestoreGroupMemberParams struct {
+	GroupID     int64
+	SnippetUuid string
+	Position    int64
+	DateAdded   sql.NullTime
+}
+
+// Adds a snippet back into a group at the position it had before
+func (q *Queries) RestoreGroupMember(ctx context.Context, arg RestoreGroupMemberParams) error {
+	_, err := q.db.ExecContext(ctx, restoreGroupMember,
+		arg.GroupID,
+		arg.SnippetUuid,
+		arg.Position,
+		arg.DateAdded,
+	)
+	return err
+}
+
 const updateGroup = `-- name: UpdateGroup :exec
 UPDATE groups
-SET group_name = ?, description = ?, uuid_list = ?, date_updated = CURRENT_TIMESTAMP
+SET group_name = ?, description = ?, date_updated = CURRENT_TIMESTAMP
 WHERE id = ?
 `
 
 type UpdateGroupParams struct {
 	GroupName   string
 	Description sql.NullString
-	UuidList    string
 	ID          int64
 }
 
 // Updates a group's details
 func (q *Queries) UpdateGroup(ctx context.Context, arg UpdateGroupParams) error {
-	_, err := q.db.ExecContext(ctx, updateGroup,
-		arg.GroupName,
-		arg.Description,
-		arg.UuidList,
-		arg.ID,
-	)
+	_, err := q.db.ExecContext(ctx, updateGroup, arg.GroupName, arg.Description, arg.ID)
 	return err
 }
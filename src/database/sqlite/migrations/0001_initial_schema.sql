@@ -1,5 +1,4 @@
--- Enable foreign key support
-PRAGMA foreign_keys = ON;
+-- Initial schema. Foreign key support is enabled per connection when the database is opened.
 
 -- Snippet Table
 CREATE TABLE snippets (
@@ -0,0 +1,3 @@
+-- Search over the latest version of each snippet. Its FTS5 index is created and kept up to date by
+-- ensureSearchIndex rather than here, as sqlite may be built without FTS5, in which case search scans the snippets.
+SELECT 1;
@@ -0,0 +1,51 @@
+-- Tags are stored once and linked to each snippet version, replacing the comma-separated snippets.tags column.
+CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,  -- Unique row ID
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE -- Tag name, stored lower case and matched case-insensitively
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL,           -- ID of the snippet version the tag belongs to
+    tag_id INTEGER NOT NULL,               -- ID of the tag
+    PRIMARY KEY (snippet_id, tag_id),
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+-- Index for finding snippets by tag
+CREATE INDEX idx_snippet_tags_tag_id ON snippet_tags(tag_id);
+
+-- Split the existing comma-separated tags of every version into rows.
+CREATE TEMP TABLE migrate_snippet_tags AS
+WITH RECURSIVE split(snippet_id, tag, rest) AS (
+    SELECT id, '', tags || ',' FROM snippets WHERE tags IS NOT NULL AND tags != ''
+    UNION ALL
+    SELECT snippet_id, LOWER(TRIM(substr(rest, 1, instr(rest, ',') - 1))), substr(rest, instr(rest, ',') + 1)
+    FROM split WHERE rest != ''
+)
+SELECT DISTINCT snippet_id, tag FROM split WHERE tag != '';
+
+INSERT OR IGNORE INTO tags (name)
+SELECT DISTINCT tag FROM migrate_snippet_tags;
+
+INSERT OR IGNORE INTO snippet_tags (snippet_id, tag_id)
+SELECT m.snippet_id, t.id FROM migrate_snippet_tags m JOIN tags t ON t.name = m.tag;
+
+DROP TABLE migrate_snippet_tags;
+
+-- The search triggers created by ensureSearchIndex must be dropped before the column, as they reference it or
+-- the FTS5 index that sqlite may have been built without. It puts them back when the database is next opened with FTS5.
+DROP TRIGGER IF EXISTS snippets_fts_insert;
+DROP TRIGGER IF EXISTS snippets_fts_update;
+DROP TRIGGER IF EXISTS snippets_fts_delete;
+
+ALTER TABLE snippets DROP COLUMN tags;
+
+-- Snippets with their tags joined back into a sorted, comma-separated list, used for reading snippets.
+CREATE VIEW snippet_details AS
+SELECT s.id, s.uuid, s.name, s.code, s.language, s.description, s.source, s.date_added, s.version, s.superseded_by,
+    (SELECT group_concat(name, ',') FROM (
+        SELECT t.name FROM snippet_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.snippet_id = s.id ORDER BY t.name
+    )) AS tags
+FROM snippets s;
@@ -0,0 +1,2 @@
+-- Groups are referenced by name from the CLI, so names must be unique ignoring case.
+CREATE UNIQUE INDEX idx_groups_group_name ON groups(group_name COLLATE NOCASE);
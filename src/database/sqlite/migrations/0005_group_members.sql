@@ -0,0 +1,49 @@
+-- Group membership moves from the comma-separated groups.uuid_list column into its own table.
+CREATE TABLE group_members (
+    group_id INTEGER NOT NULL,             -- ID of the group
+    snippet_uuid TEXT NOT NULL,            -- UUID of the member snippet (shared by all of its versions)
+    position INTEGER NOT NULL,             -- Order of the snippet within the group
+    date_added DATETIME DEFAULT CURRENT_TIMESTAMP, -- Date the snippet was added to the group
+    PRIMARY KEY (group_id, snippet_uuid),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Index for finding the groups a snippet belongs to
+CREATE INDEX idx_group_members_snippet_uuid ON group_members(snippet_uuid);
+
+-- Split the existing lists, keeping their order and dropping UUIDs of snippets that no longer exist.
+WITH RECURSIVE split(group_id, member, rest, position) AS (
+    SELECT id, '', uuid_list || ',', 0 FROM groups WHERE uuid_list != ''
+    UNION ALL
+    SELECT group_id, LOWER(TRIM(substr(rest, 1, instr(rest, ',') - 1))), substr(rest, instr(rest, ',') + 1), position + 1
+    FROM split WHERE rest != ''
+)
+INSERT OR IGNORE INTO group_members (group_id, snippet_uuid, position)
+SELECT group_id, member, position FROM split
+WHERE member != '' AND member IN (SELECT uuid FROM snippets);
+
+ALTER TABLE groups DROP COLUMN uuid_list;
+
+-- Remove a snippet from every group once its last version has been deleted.
+CREATE TRIGGER delete_group_members
+AFTER DELETE ON snippets
+FOR EACH ROW
+WHEN NOT EXISTS (SELECT 1 FROM snippets WHERE uuid = OLD.uuid)
+BEGIN
+    DELETE FROM group_members WHERE snippet_uuid = OLD.uuid;
+END;
+
+-- Membership changes count as an update to the group.
+CREATE TRIGGER group_members_insert_timestamp
+AFTER INSERT ON group_members
+FOR EACH ROW
+BEGIN
+    UPDATE groups SET date_updated = CURRENT_TIMESTAMP WHERE id = NEW.group_id;
+END;
+
+CREATE TRIGGER group_members_delete_timestamp
+AFTER DELETE ON group_members
+FOR EACH ROW
+BEGIN
+    UPDATE groups SET date_updated = CURRENT_TIMESTAMP WHERE id = OLD.group_id;
+END;
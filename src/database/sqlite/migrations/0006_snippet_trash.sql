@@ -0,0 +1,21 @@
+-- Deleting a snippet moves every version of it to the trash by setting deleted_at.
+-- Trashed snippets are excluded from listings and search until they are restored or purged.
+ALTER TABLE snippets ADD COLUMN deleted_at DATETIME;
+
+-- Index for excluding and listing trashed snippets
+CREATE INDEX idx_snippets_deleted_at ON snippets(deleted_at);
+
+DROP VIEW snippet_details;
+
+CREATE VIEW snippet_details AS
+SELECT s.id, s.uuid, s.name, s.code, s.language, s.description, s.source, s.date_added, s.version, s.superseded_by,
+    (SELECT group_concat(name, ',') FROM (
+        SELECT t.name FROM snippet_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.snippet_id = s.id ORDER BY t.name
+    )) AS tags,
+    s.deleted_at
+FROM snippets s;
+
+-- The search update trigger created by ensureSearchIndex reads snippet_details, which was just replaced.
+-- ensureSearchIndex recreates it to leave trashed snippets out of the index.
+DROP TRIGGER IF EXISTS snippets_fts_update;
@@ -0,0 +1,15 @@
+-- How often each snippet has been used, so listings can be sorted by usage
+CREATE TABLE snippet_usage (
+    snippet_uuid TEXT PRIMARY KEY,         -- UUID of the snippet (shared by all of its versions)
+    use_count INTEGER NOT NULL DEFAULT 0,  -- Number of times the snippet has been used
+    last_used DATETIME                     -- Date the snippet was last used
+);
+
+-- Forget usage once the last version of a snippet has been deleted.
+CREATE TRIGGER delete_snippet_usage
+AFTER DELETE ON snippets
+FOR EACH ROW
+WHEN NOT EXISTS (SELECT 1 FROM snippets WHERE uuid = OLD.uuid)
+BEGIN
+    DELETE FROM snippet_usage WHERE snippet_uuid = OLD.uuid;
+END;
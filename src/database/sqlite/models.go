@@ -12,21 +12,58 @@ type Group struct {
 	ID          int64
 	GroupName   string
 	Description sql.NullString
-	UuidList    string
 	DateAdded   sql.NullTime
 	DateUpdated sql.NullTime
 }
 
+type GroupMember struct {
+	GroupID     int64
+	SnippetUuid string
+	Position    int64
+	DateAdded   sql.NullTime
+}
+
 type Snippet struct {
 	ID           int64
 	Uuid         string
 	Name         sql.NullString
 	Code         string
 	Language     string
-	Tags         sql.NullString
 	Description  sql.NullString
 	Source       sql.NullString
 	DateAdded    sql.NullTime
 	Version      int64
 	SupersededBy sql.NullInt64
+	DeletedAt    sql.NullTime
+}
+
+type SnippetDetail struct {
+	ID           int64
+	Uuid         string
+	Name         sql.NullString
+	Code         string
+	Language     string
+	Description  sql.NullString
+	Source       sql.NullString
+	DateAdded    sql.NullTime
+	Version      int64
+	SupersededBy sql.NullInt64
+	Tags         sql.NullString
+	DeletedAt    sql.NullTime
+}
+
+type SnippetTag struct {
+	SnippetID int64
+	TagID     int64
+}
+
+type SnippetUsage struct {
+	SnippetUuid string
+	UseCount    int64
+	LastUsed    sql.NullTime
+}
+
+type Tag struct {
+	ID   int64
+	Name string
 }
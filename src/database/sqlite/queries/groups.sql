@@ -1,15 +1,19 @@
 -- name: CreateGroup :one
 -- Creates a new group
 INSERT INTO groups (
-    group_name, description, uuid_list, date_added, date_updated
+    group_name, description, date_added, date_updated
 ) VALUES (
-    ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 ) RETURNING *;
 
 -- name: GetGroupByID :one
 -- Get a group by its ID
 SELECT * FROM groups WHERE id = ?;
 
+-- name: GetGroupByName :one
+-- Get a group by its name, ignoring case
+SELECT * FROM groups WHERE LOWER(group_name) = LOWER(?);
+
 -- name: GetAllGroups :many
 -- Get all groups
 SELECT * FROM groups ORDER BY date_added DESC;
@@ -17,9 +21,47 @@ SELECT * FROM groups ORDER BY date_added DESC;
 -- name: UpdateGroup :exec
 -- Updates a group's details
 UPDATE groups
-SET group_name = ?, description = ?, uuid_list = ?, date_updated = CURRENT_TIMESTAMP
+SET group_name = ?, description = ?, date_updated = CURRENT_TIMESTAMP
 WHERE id = ?;
 
 -- name: DeleteGroup :exec
 -- Delete a group by its ID
 DELETE FROM groups WHERE id = ?;
+
+-- name: AddGroupMember :exec
+-- Adds a snippet to the end of a group, does nothing if it is already a member
+INSERT INTO group_members (
+    group_id, snippet_uuid, position
+) VALUES (
+    sqlc.arg(group_id), sqlc.arg(snippet_uuid), (SELECT COALESCE(MAX(position), 0) + 1 FROM group_members WHERE group_id = sqlc.arg(group_id))
+) ON CONFLICT (group_id, snippet_uuid) DO NOTHING;
+
+-- name: RemoveGroupMember :execrows
+-- Removes a snippet from a group
+DELETE FROM group_members WHERE group_id = ? AND snippet_uuid = ?;
+
+-- name: ListGroupMembers :many
+-- Get the UUIDs of the snippets in a group that are not in the trash, in group order
+SELECT snippet_uuid FROM group_members
+WHERE group_id = ?
+AND snippet_uuid NOT IN (SELECT uuid FROM snippets WHERE deleted_at IS NOT NULL)
+ORDER BY position;
+
+-- name: GetGroupNamesBySnippet :many
+-- Get the names of the groups a snippet belongs to
+SELECT g.group_name FROM groups g
+JOIN group_members gm ON gm.group_id = g.id
+WHERE gm.snippet_uuid = ?
+ORDER BY g.group_name;
+
+-- name: ListSnippetGroupMembers :many
+-- Get the group memberships of a snippet
+SELECT * FROM group_members WHERE snippet_uuid = ?;
+
+-- name: RestoreGroupMember :exec
+-- Adds a snippet back into a group at the position it had before
+INSERT INTO group_members (
+    group_id, snippet_uuid, position, date_added
+) VALUES (
+    ?, ?, ?, ?
+) ON CONFLICT (group_id, snippet_uuid) DO NOTHING;
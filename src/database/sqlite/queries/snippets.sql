@@ -1,55 +1,38 @@
 -- name: CreateSnippet :one
 -- Creates the first version of a snippet
 INSERT INTO snippets (
-    uuid, name, code, language, tags, description, source, date_added, version, superseded_by
+    uuid, name, code, language, description, source, date_added, version, superseded_by
 ) VALUES (
-    ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, NULL
+    ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, NULL
+) RETURNING *;
+
+-- name: ImportSnippet :one
+-- Creates a version of a snippet read from an export, keeping its uuid, version and date
+INSERT INTO snippets (
+    uuid, name, code, language, description, source, date_added, version, superseded_by
+) VALUES (
+    ?, ?, ?, ?, ?, ?, COALESCE(sqlc.narg(date_added), CURRENT_TIMESTAMP), ?, NULL
 ) RETURNING *;
 
 -- name: GetSnippetByID :one
 -- Get a snippet by its ID
-SELECT * FROM snippets WHERE id = ?;
+SELECT * FROM snippet_details WHERE id = ?;
 
 -- name: GetSnippetByUUID :one
 -- Get last version of a snippet by UUID
-SELECT * FROM snippets WHERE uuid = ? ORDER BY version DESC LIMIT 1;
+SELECT * FROM snippet_details WHERE uuid = ? AND deleted_at IS NULL ORDER BY version DESC LIMIT 1;
+
+-- name: GetLatestSnippetVersion :one
+-- Get last version of a snippet by UUID, including one in the trash
+SELECT * FROM snippet_details WHERE uuid = ? ORDER BY version DESC LIMIT 1;
 
 -- name: GetSnippetVersions :many
 -- Get all versions of a snippet by UUID
-SELECT * FROM snippets WHERE uuid = ? ORDER BY version DESC;
-
--- name: ListSnippetsByPage :many
--- Get all latest snippets, paginated
-SELECT * FROM snippets
-WHERE superseded_by IS NULL
-ORDER BY id DESC
-LIMIT :limit OFFSET :offset;
-
--- name: GetSnippetByLanguage :many
--- Get last version of a snippets by language
-SELECT * FROM snippets WHERE LOWER(language) = LOWER(?)
-AND superseded_by IS NULL
-ORDER BY id DESC;
-
--- name: GetSnippetByTag :many
--- Get last version of a snippets by tag
-SELECT * FROM snippets WHERE instr(tags, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC;
-
--- name: GetSnippetBySource :many
--- Get last version of a snippets by source
-SELECT * FROM snippets WHERE instr(source, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC;
-
+SELECT * FROM snippet_details WHERE uuid = ? AND deleted_at IS NULL ORDER BY version DESC;
 
--- name: GetSnippetByLanguageAndTag :many
--- Get last version of a snippets by language and tag
-SELECT * FROM snippets WHERE language = ? 
-AND instr(tags, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC;
+-- name: GetSnippetVersion :one
+-- Get a single version of a snippet by UUID
+SELECT * FROM snippet_details WHERE uuid = ? AND version = ? AND deleted_at IS NULL;
 
 -- name: MarkSnippetSuperseded :exec
 -- Marks an old snippet as superseded by a new version
@@ -61,6 +44,35 @@ WHERE id = ?;
 -- Delete a snippet by its ID
 DELETE FROM snippets WHERE id = ?;
 
+-- name: CountSnippetVersions :one
+-- Count the stored versions of a snippet by UUID, including any in the trash
+SELECT COUNT(*) FROM snippets WHERE uuid = ?;
+
 -- name: DeleteSnippetByUUID :exec
 -- Delete all versions of a snippet by UUID
-DELETE FROM snippets WHERE uuid = ?;
\ No newline at end of file
+DELETE FROM snippets WHERE uuid = ?;
+
+-- name: TrashSnippetByUUID :execrows
+-- Moves all versions of a snippet to the trash
+UPDATE snippets
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE uuid = ? AND deleted_at IS NULL;
+
+-- name: RestoreSnippetByUUID :execrows
+-- Restores all versions of a snippet from the trash
+UPDATE snippets
+SET deleted_at = NULL
+WHERE uuid = ? AND deleted_at IS NOT NULL;
+
+-- name: ListTrashedSnippets :many
+-- Get last version of the snippets in the trash, most recently deleted first
+SELECT * FROM snippet_details
+WHERE deleted_at IS NOT NULL
+AND superseded_by IS NULL
+ORDER BY deleted_at DESC, id DESC;
+
+-- name: ListTrashedSnippetUUIDs :many
+-- Get the UUIDs of the snippets moved to the trash at or before deleted_before
+SELECT DISTINCT uuid FROM snippets
+WHERE deleted_at IS NOT NULL
+AND deleted_at <= sqlc.arg('deleted_before');
@@ -0,0 +1,23 @@
+-- name: CreateTag :exec
+-- Creates a tag if it does not already exist
+INSERT INTO tags (name) VALUES (?)
+ON CONFLICT (name) DO NOTHING;
+
+-- name: AddSnippetTag :exec
+-- Links an existing tag to a snippet version
+INSERT OR IGNORE INTO snippet_tags (snippet_id, tag_id)
+VALUES (?, (SELECT id FROM tags WHERE name = ?));
+
+-- name: ListTags :many
+-- Get all tags used by the latest version of a snippet, with the number of snippets using them
+SELECT t.name, COUNT(*) AS usage_count FROM tags t
+JOIN snippet_tags st ON st.tag_id = t.id
+JOIN snippets s ON s.id = st.snippet_id
+WHERE s.superseded_by IS NULL
+AND s.deleted_at IS NULL
+GROUP BY t.id
+ORDER BY usage_count DESC, t.name;
+
+-- name: DeleteUnusedTags :exec
+-- Delete tags that are no longer linked to any snippet version
+DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM snippet_tags);
@@ -0,0 +1,19 @@
+-- name: RecordSnippetUse :exec
+-- Counts a use of a snippet
+INSERT INTO snippet_usage (
+    snippet_uuid, use_count, last_used
+) VALUES (
+    ?, 1, CURRENT_TIMESTAMP
+) ON CONFLICT (snippet_uuid) DO UPDATE SET use_count = use_count + 1, last_used = CURRENT_TIMESTAMP;
+
+-- name: GetSnippetUsage :one
+-- Get how often a snippet has been used
+SELECT * FROM snippet_usage WHERE snippet_uuid = ?;
+
+-- name: RestoreSnippetUsage :exec
+-- Sets how often a snippet has been used
+INSERT INTO snippet_usage (
+    snippet_uuid, use_count, last_used
+) VALUES (
+    ?, ?, ?
+) ON CONFLICT (snippet_uuid) DO UPDATE SET use_count = excluded.use_count, last_used = excluded.last_used;
@@ -10,12 +10,24 @@ import (
 	"database/sql"
 )
 
+const countSnippetVersions = `-- name: CountSnippetVersions :one
+SELECT COUNT(*) FROM snippets WHERE uuid = ?
+`
+
+// Count the stored versions of a snippet by UUID, including any in the trash
+func (q *Queries) CountSnippetVersions(ctx context.Context, uuid string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSnippetVersions, uuid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createSnippet = `-- name: CreateSnippet :one
 INSERT INTO snippets (
-    uuid, name, code, language, tags, description, source, date_added, version, superseded_by
+    uuid, name, code, language, description, source, date_added, version, superseded_by
 ) VALUES (
-    ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, NULL
-) RETURNING id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by
+    ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, NULL
+) RETURNING id, uuid, name, code, language, description, source, date_added, version, superseded_by, deleted_at
 `
 
 type CreateSnippetParams struct {
@@ -23,7 +35,6 @@ type CreateSnippetParams struct {
 	Name        sql.NullString
 	Code        string
 	Language    string
-	Tags        sql.NullString
 	Description sql.NullString
 	Source      sql.NullString
 	Version     int64
@@ -36,7 +47,6 @@ func (q *Queries) CreateSnippet(ctx context.Context, arg CreateSnippetParams) (S
 		arg.Name,
 		arg.Code,
 		arg.Language,
-		arg.Tags,
 		arg.Description,
 		arg.Source,
 		arg.Version,
@@ -48,12 +58,12 @@ func (q *Queries) CreateSnippet(ctx context.Context, arg CreateSnippetParams) (S
 		&i.Name,
 		&i.Code,
 		&i.Language,
-		&i.Tags,
 		&i.Description,
 		&i.Source,
 		&i.DateAdded,
 		&i.Version,
 		&i.SupersededBy,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -78,148 +88,138 @@ func (q *Queries) DeleteSnippetByUUID(ctx context.Context, uuid string) error {
 	return err
 }
 
+const getLatestSnippetVersion = `-- name: GetLatestSnippetVersion :one
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details WHERE uuid = ? ORDER BY version DESC LIMIT 1
+`
+
+// Get last version of a snippet by UUID, including one in the trash
+func (q *Queries) GetLatestSnippetVersion(ctx context.Context, uuid string) (SnippetDetail, error) {
+	row := q.db.QueryRowContext(ctx, getLatestSnippetVersion, uuid)
+	var i SnippetDetail
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.Name,
+		&i.Code,
+		&i.Language,
+		&i.Description,
+		&i.Source,
+		&i.DateAdded,
+		&i.Version,
+		&i.SupersededBy,
+		&i.Tags,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getSnippetByID = `-- name: GetSnippetByID :one
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE id = ?
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details WHERE id = ?
 `
 
 // Get a snippet by its ID
-func (q *Queries) GetSnippetByID(ctx context.Context, id int64) (Snippet, error) {
+func (q *Queries) GetSnippetByID(ctx context.Context, id int64) (SnippetDetail, error) {
 	row := q.db.QueryRowContext(ctx, getSnippetByID, id)
-	var i Snippet
+	var i SnippetDetail
 	err := row.Scan(
 		&i.ID,
 		&i.Uuid,
 		&i.Name,
 		&i.Code,
 		&i.Language,
-		&i.Tags,
 		&i.Description,
 		&i.Source,
 		&i.DateAdded,
 		&i.Version,
 		&i.SupersededBy,
+		&i.Tags,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getSnippetByLanguage = `-- name: GetSnippetByLanguage :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE LOWER(language) = LOWER(?)
-AND superseded_by IS NULL
-ORDER BY id DESC
+const getSnippetByUUID = `-- name: GetSnippetByUUID :one
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details WHERE uuid = ? AND deleted_at IS NULL ORDER BY version DESC LIMIT 1
 `
 
-// Get last version of a snippets by language
-func (q *Queries) GetSnippetByLanguage(ctx context.Context, lower string) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, getSnippetByLanguage, lower)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Snippet
-	for rows.Next() {
-		var i Snippet
-		if err := rows.Scan(
-			&i.ID,
-			&i.Uuid,
-			&i.Name,
-			&i.Code,
-			&i.Language,
-			&i.Tags,
-			&i.Description,
-			&i.Source,
-			&i.DateAdded,
-			&i.Version,
-			&i.SupersededBy,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// Get last version of a snippet by UUID
+func (q *Queries) GetSnippetByUUID(ctx context.Context, uuid string) (SnippetDetail, error) {
+	row := q.db.QueryRowContext(ctx, getSnippetByUUID, uuid)
+	var i SnippetDetail
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.Name,
+		&i.Code,
+		&i.Language,
+		&i.Description,
+		&i.Source,
+		&i.DateAdded,
+		&i.Version,
+		&i.SupersededBy,
+		&i.Tags,
+		&i.DeletedAt,
+	)
+	return i, err
 }
 
-const getSnippetByLanguageAndTag = `-- name: GetSnippetByLanguageAndTag :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE language = ? 
-AND instr(tags, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC
+const getSnippetVersion = `-- name: GetSnippetVersion :one
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details WHERE uuid = ? AND version = ? AND deleted_at IS NULL
 `
 
-type GetSnippetByLanguageAndTagParams struct {
-	Language string
-	INSTR    string
+type GetSnippetVersionParams struct {
+	Uuid    string
+	Version int64
 }
 
-// Get last version of a snippets by language and tag
-func (q *Queries) GetSnippetByLanguageAndTag(ctx context.Context, arg GetSnippetByLanguageAndTagParams) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, getSnippetByLanguageAndTag, arg.Language, arg.INSTR)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Snippet
-	for rows.Next() {
-		var i Snippet
-		if err := rows.Scan(
-			&i.ID,
-			&i.Uuid,
-			&i.Name,
-			&i.Code,
-			&i.Language,
-			&i.Tags,
-			&i.Description,
-			&i.Source,
-			&i.DateAdded,
-			&i.Version,
-			&i.SupersededBy,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// Get a single version of a snippet by UUID
+func (q *Queries) GetSnippetVersion(ctx context.Context, arg GetSnippetVersionParams) (SnippetDetail, error) {
+	row := q.db.QueryRowContext(ctx, getSnippetVersion, arg.Uuid, arg.Version)
+	var i SnippetDetail
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.Name,
+		&i.Code,
+		&i.Language,
+		&i.Description,
+		&i.Source,
+		&i.DateAdded,
+		&i.Version,
+		&i.SupersededBy,
+		&i.Tags,
+		&i.DeletedAt,
+	)
+	return i, err
 }
 
-const getSnippetBySource = `-- name: GetSnippetBySource :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE instr(source, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC
+const getSnippetVersions = `-- name: GetSnippetVersions :many
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details WHERE uuid = ? AND deleted_at IS NULL ORDER BY version DESC
 `
 
-// Get last version of a snippets by source
-func (q *Queries) GetSnippetBySource(ctx context.Context, instr string) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, getSnippetBySource, instr)
+// Get all versions of a snippet by UUID
+func (q *Queries) GetSnippetVersions(ctx context.Context, uuid string) ([]SnippetDetail, error) {
+	rows, err := q.db.QueryContext(ctx, getSnippetVersions, uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Snippet
+	var items []SnippetDetail
 	for rows.Next() {
-		var i Snippet
+		var i SnippetDetail
 		if err := rows.Scan(
 			&i.ID,
 			&i.Uuid,
 			&i.Name,
 			&i.Code,
 			&i.Language,
-			&i.Tags,
 			&i.Description,
 			&i.Source,
 			&i.DateAdded,
 			&i.Version,
 			&i.SupersededBy,
+			&i.Tags,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -234,55 +234,37 @@ func (q *Queries) GetSnippetBySource(ctx context.Context, instr string) ([]Snipp
 	return items, nil
 }
 
-const getSnippetByTag = `-- name: GetSnippetByTag :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE instr(tags, ?) > 0 
-AND superseded_by IS NULL
-ORDER BY id DESC
+const importSnippet = `-- name: ImportSnippet :one
+INSERT INTO snippets (
+    uuid, name, code, language, description, source, date_added, version, superseded_by
+) VALUES (
+    ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), ?, NULL
+) RETURNING id, uuid, name, code, language, description, source, date_added, version, superseded_by, deleted_at
 `
 
-// Get last version of a snippets by tag
-func (q *Queries) GetSnippetByTag(ctx context.Context, instr string) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, getSnippetByTag, instr)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Snippet
-	for rows.Next() {
-		var i Snippet
-		if err := rows.Scan(
-			&i.ID,
-			&i.Uuid,
-			&i.Name,
-			&i.Code,
-			&i.Language,
-			&i.Tags,
-			&i.Description,
-			&i.Source,
-			&i.DateAdded,
-			&i.Version,
-			&i.SupersededBy,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type ImportSnippetParams struct {
+	Uuid        string
+	Name        sql.NullString
+	Code        string
+	Language    string
+	Description sql.NullString
+	Source      sql.NullString
+	DateAdded   sql.NullTime
+	Version     int64
 }
 
-const getSnippetByUUID = `-- name: GetSnippetByUUID :one
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE uuid = ? ORDER BY version DESC LIMIT 1
-`
-
-// Get last version of a snippet by UUID
-func (q *Queries) GetSnippetByUUID(ctx context.Context, uuid string) (Snippet, error) {
-	row := q.db.QueryRowContext(ctx, getSnippetByUUID, uuid)
+// Creates a version of a snippet read from an export, keeping its uuid, version and date
+func (q *Queries) ImportSnippet(ctx context.Context, arg ImportSnippetParams) (Snippet, error) {
+	row := q.db.QueryRowContext(ctx, importSnippet,
+		arg.Uuid,
+		arg.Name,
+		arg.Code,
+		arg.Language,
+		arg.Description,
+		arg.Source,
+		arg.DateAdded,
+		arg.Version,
+	)
 	var i Snippet
 	err := row.Scan(
 		&i.ID,
@@ -290,46 +272,36 @@ func (q *Queries) GetSnippetByUUID(ctx context.Context, uuid string) (Snippet, e
 		&i.Name,
 		&i.Code,
 		&i.Language,
-		&i.Tags,
 		&i.Description,
 		&i.Source,
 		&i.DateAdded,
 		&i.Version,
 		&i.SupersededBy,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getSnippetVersions = `-- name: GetSnippetVersions :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets WHERE uuid = ? ORDER BY version DESC
+const listTrashedSnippetUUIDs = `-- name: ListTrashedSnippetUUIDs :many
+SELECT DISTINCT uuid FROM snippets
+WHERE deleted_at IS NOT NULL
+AND deleted_at <= ?
 `
 
-// Get all versions of a snippet by UUID
-func (q *Queries) GetSnippetVersions(ctx context.Context, uuid string) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, getSnippetVersions, uuid)
+// Get the UUIDs of the snippets moved to the trash at or before deleted_before
+func (q *Queries) ListTrashedSnippetUUIDs(ctx context.Context, deletedBefore sql.NullTime) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listTrashedSnippetUUIDs, deletedBefore)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Snippet
+	var items []string
 	for rows.Next() {
-		var i Snippet
-		if err := rows.Scan(
-			&i.ID,
-			&i.Uuid,
-			&i.Name,
-			&i.Code,
-			&i.Language,
-			&i.Tags,
-			&i.Description,
-			&i.Source,
-			&i.DateAdded,
-			&i.Version,
-			&i.SupersededBy,
-		); err != nil {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, uuid)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -340,40 +312,36 @@ func (q *Queries) GetSnippetVersions(ctx context.Context, uuid string) ([]Snippe
 	return items, nil
 }
 
-const listSnippetsByPage = `-- name: ListSnippetsByPage :many
-SELECT id, uuid, name, code, language, tags, description, source, date_added, version, superseded_by FROM snippets
-WHERE superseded_by IS NULL
-ORDER BY id DESC
-LIMIT ?2 OFFSET ?1
+const listTrashedSnippets = `-- name: ListTrashedSnippets :many
+SELECT id, uuid, name, code, language, description, source, date_added, version, superseded_by, tags, deleted_at FROM snippet_details
+WHERE deleted_at IS NOT NULL
+AND superseded_by IS NULL
+ORDER BY deleted_at DESC, id DESC
 `
 
-type ListSnippetsByPageParams struct {
-	Offset int64
-	Limit  int64
-}
-
-// Get all latest snippets, paginated
-func (q *Queries) ListSnippetsByPage(ctx context.Context, arg ListSnippetsByPageParams) ([]Snippet, error) {
-	rows, err := q.db.QueryContext(ctx, listSnippetsByPage, arg.Offset, arg.Limit)
+// Get last version of the snippets in the trash, most recently deleted first
+func (q *Queries) ListTrashedSnippets(ctx context.Context) ([]SnippetDetail, error) {
+	rows, err := q.db.QueryContext(ctx, listTrashedSnippets)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Snippet
+	var items []SnippetDetail
 	for rows.Next() {
-		var i Snippet
+		var i SnippetDetail
 		if err := rows.Scan(
 			&i.ID,
 			&i.Uuid,
 			&i.Name,
 			&i.Code,
 			&i.Language,
-			&i.Tags,
 			&i.Description,
 			&i.Source,
 			&i.DateAdded,
 			&i.Version,
 			&i.SupersededBy,
+			&i.Tags,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -404,3 +372,33 @@ func (q *Queries) MarkSnippetSuperseded(ctx context.Context, arg MarkSnippetSupe
 	_, err := q.db.ExecContext(ctx, markSnippetSuperseded, arg.SupersededBy, arg.ID)
 	return err
 }
+
+const restoreSnippetByUUID = `-- name: RestoreSnippetByUUID :execrows
+UPDATE snippets
+SET deleted_at = NULL
+WHERE uuid = ? AND deleted_at IS NOT NULL
+`
+
+// Restores all versions of a snippet from the trash
+func (q *Queries) RestoreSnippetByUUID(ctx context.Context, uuid string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreSnippetByUUID, uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const trashSnippetByUUID = `-- name: TrashSnippetByUUID :execrows
+UPDATE snippets
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE uuid = ? AND deleted_at IS NULL
+`
+
+// Moves all versions of a snippet to the trash
+func (q *Queries) TrashSnippetByUUID(ctx context.Context, uuid string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, trashSnippetByUUID, uuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
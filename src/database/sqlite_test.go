@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Ryan-Har/csnip/common/models"
+)
+
+func newTestHandler(t *testing.T, dbLoc string) *SQLiteHandler {
+	t.Helper()
+	db, err := NewSQLiteHandler(dbLoc)
+	if err != nil {
+		t.Fatalf("opening %s: %v", dbLoc, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.(*SQLiteHandler)
+}
+
+// TestConcurrentVersions updates and reverts one snippet from many goroutines through two handlers on the same file,
+// as the daemon and the cli would, and checks every version was built on the one before it
+func TestConcurrentVersions(t *testing.T) {
+	ctx := context.Background()
+	dbLoc := filepath.Join(t.TempDir(), "csnip.db")
+	handlers := []*SQLiteHandler{newTestHandler(t, dbLoc), newTestHandler(t, dbLoc)}
+
+	snippet, err := handlers[0].AddNewSnippet(ctx, models.CodeSnippet{Name: "race", Code: "echo 0", Language: "bash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 40
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db := handlers[i%len(handlers)]
+			var err error
+			if i%4 == 3 {
+				_, err = db.RevertSnippet(ctx, snippet.Uuid, 1)
+			} else {
+				_, err = db.UpdateSnippet(ctx, snippet.Uuid, models.CodeSnippet{Code: fmt.Sprintf("echo %d", i+1)})
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := handlers[0].GetSnippetHistoryByUUID(ctx, snippet.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, version := range history {
+		versions = append(versions, version.Version)
+	}
+	slices.Sort(versions)
+	for i, version := range versions {
+		if version != int64(i+1) {
+			t.Fatalf("versions are %v, want 1 to %d", versions, n+1)
+		}
+	}
+	if len(versions) != n+1 {
+		t.Fatalf("got %d versions, want %d", len(versions), n+1)
+	}
+
+	var latest int
+	err = handlers[0].database.QueryRow("SELECT COUNT(*) FROM snippets WHERE uuid = ? AND superseded_by IS NULL", snippet.Uuid.String()).Scan(&latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != 1 {
+		t.Fatalf("%d versions are not superseded, the chain forked", latest)
+	}
+
+	// every other version is superseded by a different row
+	var superseding int
+	err = handlers[0].database.QueryRow("SELECT COUNT(DISTINCT superseded_by) FROM snippets WHERE uuid = ? AND superseded_by IS NOT NULL", snippet.Uuid.String()).Scan(&superseding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if superseding != n {
+		t.Fatalf("%d distinct superseding versions, want %d", superseding, n)
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/google/uuid"
+)
+
+// GetDeletedSnippets returns the last version of every snippet in the trash, most recently deleted first
+func (s *SQLiteHandler) GetDeletedSnippets(ctx context.Context) ([]models.CodeSnippet, error) {
+	var responseSnippets []models.CodeSnippet
+
+	dbSnippets, err := s.queries.ListTrashedSnippets(ctx)
+	if err != nil {
+		return responseSnippets, fmt.Errorf("failed to retrieve deleted snippets: %w", err)
+	}
+
+	for _, snippet := range dbSnippets {
+		responseSnippets = append(responseSnippets, convertSqliteSnippetDetailToCodeSnippet(snippet))
+	}
+	return responseSnippets, nil
+}
+
+// RestoreSnippetByUUID takes every version of the snippet back out of the trash
+func (s *SQLiteHandler) RestoreSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	restored, err := s.queries.RestoreSnippetByUUID(ctx, u.String())
+	if err != nil {
+		return fmt.Errorf("failed to restore snippet by uuid: %w", err)
+	}
+	if restored == 0 {
+		return ErrSnippetNotInTrash
+	}
+	return nil
+}
+
+// PurgeSnippetByUUID permanently deletes every version of the snippet, whether or not it is in the trash
+func (s *SQLiteHandler) PurgeSnippetByUUID(ctx context.Context, u uuid.UUID) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	// the history trigger removes the rows itself, so the delete cannot report whether anything matched
+	versions, err := s.queries.CountSnippetVersions(ctx, u.String())
+	if err != nil {
+		return fmt.Errorf("failed to check for snippet: %w", err)
+	}
+	if versions == 0 {
+		return ErrNoSnippetsFound
+	}
+
+	err = s.queries.DeleteSnippetByUUID(ctx, u.String())
+	if err != nil {
+		return fmt.Errorf("failed to purge snippet by uuid: %w", err)
+	}
+
+	err = s.queries.DeleteUnusedTags(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+	return nil
+}
+
+// EmptyTrash permanently deletes the snippets that have been in the trash for at least olderThan,
+// a zero duration empties the whole trash. It returns the number of snippets deleted.
+func (s *SQLiteHandler) EmptyTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	// deleted_at is stored by sqlite in UTC
+	deletedBefore := sql.NullTime{
+		Time:  time.Now().UTC().Add(-olderThan),
+		Valid: true,
+	}
+
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	q := s.queries.WithTx(tx)
+
+	uuids, err := q.ListTrashedSnippetUUIDs(ctx, deletedBefore)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to retrieve deleted snippets: %w", err)
+	}
+
+	for _, u := range uuids {
+		if err := q.DeleteSnippetByUUID(ctx, u); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to purge snippet %s: %w", u, err)
+		}
+	}
+
+	if err := q.DeleteUnusedTags(ctx); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(uuids), nil
+}
@@ -0,0 +1,291 @@
+// Package diff compares versions of a code snippet, it is shared by every front end that shows snippet history.
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/google/uuid"
+)
+
+// LineKind marks whether a line of code is unchanged, removed or added
+type LineKind int
+
+const (
+	LineEqual LineKind = iota
+	LineDelete
+	LineInsert
+)
+
+// Line is a single line of a code diff
+type Line struct {
+	Kind LineKind
+	Text string
+}
+
+// FieldChange is a snippet field whose value differs between two versions
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// VersionDiff holds every difference between two versions of a snippet
+type VersionDiff struct {
+	Uuid   uuid.UUID
+	From   models.CodeSnippet
+	To     models.CodeSnippet
+	Fields []FieldChange
+	Code   []Line
+}
+
+// ErrSingleVersion is returned when a snippet has no earlier version to compare against
+var ErrSingleVersion = errors.New("snippet only has a single version")
+
+// DiffVersions compares two versions of the snippet.
+// A zero to compares against the latest version and a zero from compares against the version before to.
+func DiffVersions(ctx context.Context, db database.DatabaseInteractions, u uuid.UUID, from int64, to int64) (VersionDiff, error) {
+	versions, err := db.GetSnippetHistoryByUUID(ctx, u)
+	if err != nil {
+		return VersionDiff{}, err
+	}
+	if len(versions) == 0 {
+		return VersionDiff{}, database.ErrNoSnippetsFound
+	}
+
+	if to == 0 {
+		for _, v := range versions {
+			to = max(to, v.Version)
+		}
+	}
+	if from == 0 {
+		if to == 1 {
+			return VersionDiff{}, ErrSingleVersion
+		}
+		from = to - 1
+	}
+
+	fromSnippet, ok := findVersion(versions, from)
+	if !ok {
+		return VersionDiff{}, fmt.Errorf("version %d: %w", from, database.ErrVersionNotFound)
+	}
+	toSnippet, ok := findVersion(versions, to)
+	if !ok {
+		return VersionDiff{}, fmt.Errorf("version %d: %w", to, database.ErrVersionNotFound)
+	}
+
+	return Compare(fromSnippet, toSnippet), nil
+}
+
+// Compare returns the differences between two snippets
+func Compare(from, to models.CodeSnippet) VersionDiff {
+	return VersionDiff{
+		Uuid:   to.Uuid,
+		From:   from,
+		To:     to,
+		Fields: ChangedFields(from, to),
+		Code:   Lines(from.Code, to.Code),
+	}
+}
+
+// ChangedFields lists the user editable fields, other than the code, that differ between two snippets
+func ChangedFields(from, to models.CodeSnippet) []FieldChange {
+	fields := []FieldChange{
+		{"name", from.Name, to.Name},
+		{"language", from.Language, to.Language},
+		{"tags", strings.Join(from.Tags, ","), strings.Join(to.Tags, ",")},
+		{"description", from.Description, to.Description},
+		{"source", from.Source, to.Source},
+	}
+	return slices.DeleteFunc(fields, func(f FieldChange) bool {
+		return f.Old == f.New
+	})
+}
+
+// Lines returns a line diff turning from into to, based on the longest common subsequence of lines
+func Lines(from, to string) []Line {
+	a, b := splitLines(from), splitLines(to)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{LineEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{LineDelete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{LineInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{LineDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{LineInsert, b[j]})
+	}
+	return lines
+}
+
+// Changed reports whether the two versions differ at all
+func (d VersionDiff) Changed() bool {
+	if len(d.Fields) > 0 {
+		return true
+	}
+	return slices.ContainsFunc(d.Code, func(l Line) bool {
+		return l.Kind != LineEqual
+	})
+}
+
+// Unified formats the diff in unified diff format. Field changes are listed ahead of the --- header, one quoted
+// line each, where patch and other diff tools skip them as they would a commit message.
+// context is the number of unchanged lines shown around each change.
+func (d VersionDiff) Unified(context int) string {
+	var sb strings.Builder
+	for _, f := range d.Fields {
+		fmt.Fprintf(&sb, "%s: %q -> %q\n", f.Field, f.Old, f.New)
+	}
+
+	codeHunks := hunks(d.Code, context)
+	if len(codeHunks) == 0 {
+		return sb.String()
+	}
+	if len(d.Fields) > 0 {
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "--- %s version %d\n", d.Uuid, d.From.Version)
+	fmt.Fprintf(&sb, "+++ %s version %d\n", d.Uuid, d.To.Version)
+	for _, h := range codeHunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldCount), hunkRange(h.newStart, h.newCount))
+		for _, l := range h.lines {
+			switch l.Kind {
+			case LineDelete:
+				sb.WriteString("-")
+			case LineInsert:
+				sb.WriteString("+")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []Line
+}
+
+// hunks groups changed lines together with up to context unchanged lines either side,
+// changes with at most twice the context unchanged lines between them share a hunk
+func hunks(lines []Line, context int) []hunk {
+	var result []hunk
+	var current *hunk
+	oldLine, newLine := 0, 0
+	lastChange := -1
+
+	for idx, l := range lines {
+		if l.Kind != LineEqual {
+			if current == nil || idx-lastChange-1 > 2*context {
+				if current != nil {
+					result = append(result, closeHunk(*current, lines, lastChange, context))
+				}
+				start := max(idx-context, 0)
+				current = &hunk{
+					oldStart: oldLine - (idx - start),
+					newStart: newLine - (idx - start),
+					lines:    slices.Clone(lines[start:idx]),
+				}
+			} else {
+				current.lines = append(current.lines, lines[lastChange+1:idx]...)
+			}
+			current.lines = append(current.lines, l)
+			lastChange = idx
+		}
+
+		switch l.Kind {
+		case LineEqual:
+			oldLine++
+			newLine++
+		case LineDelete:
+			oldLine++
+		case LineInsert:
+			newLine++
+		}
+	}
+
+	if current != nil {
+		result = append(result, closeHunk(*current, lines, lastChange, context))
+	}
+	return result
+}
+
+// closeHunk adds the unchanged lines following the last change in a hunk and counts its lines
+func closeHunk(h hunk, lines []Line, lastChange int, context int) hunk {
+	end := min(lastChange+1+context, len(lines))
+	h.lines = append(h.lines, lines[lastChange+1:end]...)
+	for _, l := range h.lines {
+		switch l.Kind {
+		case LineEqual:
+			h.oldCount++
+			h.newCount++
+		case LineDelete:
+			h.oldCount++
+		case LineInsert:
+			h.newCount++
+		}
+	}
+	return h
+}
+
+// hunkRange formats the start and length of a hunk, empty ranges point at the line before them
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func findVersion(versions []models.CodeSnippet, version int64) (models.CodeSnippet, bool) {
+	for _, v := range versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return models.CodeSnippet{}, false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
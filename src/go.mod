@@ -4,12 +4,21 @@ go 1.23.5
 
 require github.com/mattn/go-sqlite3 v1.14.24
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/alecthomas/chroma/v2 v2.15.0
+	github.com/atotto/clipboard v0.1.4
+	github.com/gdamore/tcell/v2 v2.8.1
+	github.com/google/uuid v1.6.0
+	github.com/mattn/go-runewidth v0.0.16
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/alecthomas/chroma v0.10.0 // indirect
-	github.com/alecthomas/chroma/v2 v2.15.0 // indirect
-	github.com/atotto/clipboard v0.1.4 // indirect
 	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/gdamore/encoding v1.0.1 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/term v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 )
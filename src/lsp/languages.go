@@ -0,0 +1,26 @@
+package lsp
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/Ryan-Har/csnip/common"
+)
+
+// documentLanguage is the snippet language for a document, falling back to the file name when the client's
+// language identifier is unknown
+func documentLanguage(languageID string, uri string) string {
+	// plain text documents are left to the file name, an editor uses it for any file type it does not know
+	if languageID != "plaintext" {
+		if lang, ok := common.LanguageFromEditorID(languageID); ok {
+			return lang
+		}
+	}
+
+	if u, err := url.Parse(uri); err == nil && u.Path != "" {
+		if lang, ok := common.DetectLanguage(path.Base(u.Path), ""); ok {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,248 @@
+// Package lsp serves snippets to editors as completions over the language server protocol
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/database"
+)
+
+// codeServerNotInitialized is returned for requests sent before initialize
+const codeServerNotInitialized = -32002
+
+// ErrExitWithoutShutdown is returned when the client sends exit without asking the server to shut down first
+var ErrExitWithoutShutdown = errors.New("language server exited without a shutdown request")
+
+// Options controls how the server notices changes to the libraries
+type Options struct {
+	// Watch is the database files checked before each completion, the cached completions are dropped when any
+	// of them change so snippets saved elsewhere show up without restarting the editor
+	Watch []string
+}
+
+type server struct {
+	db    database.DatabaseInteractions
+	conn  *conn
+	watch []string
+
+	initialized bool
+	shutdown    bool
+	documents   map[string]string // uri to snippet language
+
+	stamp string
+	cache map[string][]completionItem // language to its completions
+}
+
+// Run serves requests read from in until the client exits, in is closed or ctx is cancelled
+func Run(ctx context.Context, db database.DatabaseInteractions, in io.Reader, out io.Writer, opts Options) error {
+	s := &server{
+		db:        db,
+		conn:      newConn(in, out),
+		watch:     opts.Watch,
+		documents: map[string]string{},
+		cache:     map[string][]completionItem{},
+	}
+
+	// messages are read in the background so a cancelled ctx is not stuck behind a blocking read
+	type result struct {
+		msg message
+		err error
+	}
+	messages := make(chan result)
+	go func() {
+		for {
+			msg, err := s.conn.read()
+			select {
+			case messages <- result{msg, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case r := <-messages:
+			if r.err == io.EOF {
+				return nil
+			}
+			var rpcErr *responseError
+			if errors.As(r.err, &rpcErr) {
+				// the body could not be decoded so there is no id to answer with
+				if err := s.conn.write(message{ID: json.RawMessage("null"), Error: rpcErr}); err != nil {
+					return err
+				}
+				continue
+			}
+			if r.err != nil {
+				return r.err
+			}
+
+			if r.msg.Method == "exit" {
+				if !s.shutdown {
+					return ErrExitWithoutShutdown
+				}
+				return nil
+			}
+			if err := s.handle(ctx, r.msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle answers a single message, only failures to write to the client are returned
+func (s *server) handle(ctx context.Context, msg message) error {
+	if msg.isNotification() {
+		s.handleNotification(msg)
+		return nil
+	}
+
+	switch {
+	case msg.Method == "initialize":
+		s.initialized = true
+		return s.conn.reply(msg.ID, initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncOptions{OpenClose: true},
+				CompletionProvider: completionOptions{},
+			},
+			ServerInfo: serverInfo{Name: "csnip"},
+		}, nil)
+	case !s.initialized:
+		return s.conn.reply(msg.ID, nil, &responseError{Code: codeServerNotInitialized, Message: "initialize has not been requested"})
+	case s.shutdown:
+		return s.conn.reply(msg.ID, nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"})
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return s.conn.reply(msg.ID, nil, nil)
+	case "textDocument/completion":
+		var params completionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.conn.reply(msg.ID, nil, &responseError{Code: codeInvalidParams, Message: err.Error()})
+		}
+		items, err := s.completions(ctx, params.TextDocument.URI)
+		if err != nil {
+			s.logError(err)
+		}
+		return s.conn.reply(msg.ID, completionList{Items: items}, err)
+	default:
+		return s.conn.reply(msg.ID, nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method})
+	}
+}
+
+func (s *server) handleNotification(msg message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			s.documents[params.TextDocument.URI] = documentLanguage(params.TextDocument.LanguageID, params.TextDocument.URI)
+		}
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.documents, params.TextDocument.URI)
+		}
+	}
+}
+
+// completions returns the snippets in the document's language, most used first
+func (s *server) completions(ctx context.Context, uri string) ([]completionItem, error) {
+	lang, ok := s.documents[uri]
+	if !ok {
+		// a client may ask before or without sending didOpen, the file name is all there is to go on
+		lang = documentLanguage("", uri)
+	}
+	if lang == "" {
+		return []completionItem{}, nil
+	}
+
+	if stamp := s.libraryStamp(); stamp != s.stamp {
+		s.stamp = stamp
+		clear(s.cache)
+	}
+	if items, ok := s.cache[lang]; ok {
+		return items, nil
+	}
+
+	snippets, err := s.db.QuerySnippets(ctx, database.SnippetFilter{
+		Languages: []string{lang},
+		Sort:      database.SortByUsage,
+	})
+	if err != nil && !errors.Is(err, database.ErrNoSnippetsFound) {
+		return []completionItem{}, fmt.Errorf("failed to get %s snippets: %w", lang, err)
+	}
+
+	items := make([]completionItem, 0, len(snippets))
+	for i, snippet := range snippets {
+		items = append(items, completion(snippet, i))
+	}
+	s.cache[lang] = items
+	return items, nil
+}
+
+// libraryStamp changes whenever any watched database file is written to, including its write ahead log
+func (s *server) libraryStamp() string {
+	var b strings.Builder
+	for _, path := range s.watch {
+		for _, name := range []string{path, path + "-wal"} {
+			if info, err := os.Stat(name); err == nil {
+				fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	}
+	return b.String()
+}
+
+func (s *server) logError(err error) {
+	s.conn.notify("window/logMessage", logMessageParams{Type: messageTypeError, Message: "csnip: " + err.Error()})
+}
+
+// completion is the item for a snippet, rank keeps the editor listing snippets in the order they were queried
+func completion(snippet models.CodeSnippet, rank int) completionItem {
+	item := completionItem{
+		Label:            snippet.Name,
+		Kind:             completionItemKindSnippet,
+		Detail:           strings.Join(snippet.Tags, ", "),
+		SortText:         fmt.Sprintf("%06d", rank),
+		FilterText:       snippet.Name,
+		InsertText:       escapeSnippet(snippet.Code),
+		InsertTextFormat: insertTextFormatSnippet,
+	}
+	if item.Label == "" {
+		item.Label = snippet.Uuid.String()
+	}
+	if snippet.Library != "" {
+		item.LabelDetails = &labelDetails{Description: snippet.Library}
+	}
+
+	var doc strings.Builder
+	if snippet.Description != "" {
+		doc.WriteString(snippet.Description)
+		doc.WriteString("\n\n")
+	}
+	fmt.Fprintf(&doc, "```%s\n%s\n```", snippet.Language, strings.TrimRight(snippet.Code, "\n"))
+	item.Documentation = &markupContent{Kind: "markdown", Value: doc.String()}
+	return item
+}
+
+var snippetEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`)
+
+// escapeSnippet escapes the characters that have a meaning in snippet syntax so the code is inserted as written
+func escapeSnippet(code string) string {
+	return snippetEscaper.Replace(code)
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/Ryan-Har/csnip/config"
+	"github.com/Ryan-Har/csnip/daemon"
 	"github.com/Ryan-Har/csnip/database"
+	"github.com/Ryan-Har/csnip/lsp"
 	"github.com/Ryan-Har/csnip/options"
+	"github.com/Ryan-Har/csnip/tui"
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
@@ -14,35 +22,109 @@ import (
 )
 
 func main() {
-	db, err := database.NewSQLiteHandler()
+	opt, err := options.GetOptions()
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("error getting options: ", err)
 	}
 
-	opt, err := options.GetOptions()
-	if err != nil {
-		log.Fatal("error getting options", err)
+	// cancelled on ctrl+c or SIGTERM so in flight queries are aborted rather than left running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var db database.DatabaseInteractions
+	if opt.RunType != options.RunTypeCli || opt.CliOpts.RequiresDatabase() {
+		db, err = openDatabase(opt.Config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
 	}
 
 	switch opt.RunType {
 	case options.RunTypeCli:
-		opt.CliOpts.Run(db)
+		opt.CliOpts.Run(ctx, db)
 	case options.RunTypeTui:
-		fmt.Println("running as tui")
+		if err := tui.Run(ctx, db, opt.Config); err != nil {
+			log.Fatal(err)
+		}
 	case options.RunTypeDaemon:
-		fmt.Println("running as daemon")
+		err := daemon.Run(ctx, db, daemon.Options{
+			Socket: opt.Config.Socket,
+			Listen: opt.Config.Listen,
+			Token:  opt.Config.Token,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	case options.RunTypeLsp:
+		watch := []string{opt.Config.Database}
+		for _, lib := range opt.Config.Libraries {
+			watch = append(watch, lib.Path)
+		}
+		if err := lsp.Run(ctx, db, os.Stdin, os.Stdout, lsp.Options{Watch: watch}); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	//exampleUseOfChroma()
 }
 
+// openDatabase opens the personal database, layered with any libraries in the config.
+// Libraries other than the one written to are opened read only, they are often shared and may not be writable,
+// and are only opened for writing when a snippet is copied to them.
+func openDatabase(cfg config.Config) (database.DatabaseInteractions, error) {
+	personal, err := database.NewSQLiteHandler(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Libraries) == 0 {
+		return personal, nil
+	}
+
+	libraries := []database.Library{{Name: config.PersonalLibrary, DB: personal}}
+	closeAll := func() {
+		for _, lib := range libraries {
+			lib.DB.Close()
+		}
+	}
+	for _, lib := range cfg.Libraries {
+		// a missing library is most likely a typo or a repository that has not been cloned, do not create an empty one
+		if _, err := os.Stat(lib.Path); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("unable to open library %s: %w", lib.Name, err)
+		}
+		library := database.Library{Name: lib.Name}
+		if strings.EqualFold(lib.Name, cfg.WriteLibrary) {
+			library.DB, err = database.NewSQLiteHandler(lib.Path)
+		} else {
+			library.DB, err = database.NewReadOnlySQLiteHandler(lib.Path)
+			library.OpenWritable = func() (database.DatabaseInteractions, error) {
+				return database.NewSQLiteHandler(lib.Path)
+			}
+		}
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("unable to open library %s: %w", lib.Name, err)
+		}
+		libraries = append(libraries, library)
+	}
+
+	layered, err := database.NewLayeredHandler(libraries, cfg.WriteLibrary)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return layered, nil
+}
+
 func exampleUseOfChroma() {
-	db, err := database.NewSQLiteHandler()
+	db, err := database.NewSQLiteHandler(config.Default().Database)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer db.Close()
 
-	snip, err := db.GetSnippetByUUID(uuid.MustParse("cde66214-d303-4f5c-8bf5-ca9ae60dc36f"))
+	snip, err := db.GetSnippetByUUID(context.Background(), uuid.MustParse("cde66214-d303-4f5c-8bf5-ca9ae60dc36f"))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -1,5 +0,0 @@
-package options
-
-const (
-	CodeSyntaxHighlightingTheme = "monokai"
-)
@@ -0,0 +1,166 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleExportFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeExport
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	langFlag := exportCmd.String("l", "", "Only export code snippets matching any of a comma seperated list of languages")
+	tagFlag := exportCmd.String("t", "", "Only export code snippets matching any of a comma seperated list of tags")
+	allTagsFlag := exportCmd.Bool("all-tags", false, "Only match code snippets that have every tag provided with -t")
+	groupFlag := exportCmd.String("g", "", "Only export the code snippets in the group")
+	sourceFlag := exportCmd.String("s", "", "Only export code snippets whose source contains the text")
+	nameFlag := exportCmd.String("n", "", "Only export code snippets whose name contains the text, * and ? can be used as wildcards")
+	sinceFlag := exportCmd.String("since", "", "Only code snippets changed since a date (2006-01-02) or age (30d)")
+	beforeFlag := exportCmd.String("before", "", "Only code snippets last changed before a date (2006-01-02) or age (30d)")
+	historyFlag := exportCmd.Bool("with-history", false, "Export every version of each code snippet rather than only the latest")
+	pathFlag := exportCmd.String("o", "", "File to write the export to, the format is taken from its extension. Defaults to stdout")
+	outputFlag := exportCmd.String("output", "", "Export format, one of "+strings.Join(cli.ExportFormats, ", ")+". Defaults to the extension of -o, or json")
+
+	exportCmd.Parse(args)
+	if exportCmd.Parsed() {
+		if *outputFlag != "" && !slices.Contains(cli.ExportFormats, *outputFlag) {
+			fmt.Println("Unknown export format: ", *outputFlag)
+			exportCmd.Usage()
+			os.Exit(1)
+		}
+		if *allTagsFlag {
+			cliOpts.FlagOptions[cli.FlagOptionTagMatch] = "all"
+		}
+		if *historyFlag {
+			cliOpts.FlagOptions[cli.FlagOptionHistory] = "true"
+		}
+
+		// only the options that were set are added
+		options := map[cli.FlagOption]string{
+			cli.FlagOptionLanguage: *langFlag,
+			cli.FlagOptionTag:      *tagFlag,
+			cli.FlagOptionGroup:    *groupFlag,
+			cli.FlagOptionSource:   *sourceFlag,
+			cli.FlagOptionName:     *nameFlag,
+			cli.FlagOptionSince:    *sinceFlag,
+			cli.FlagOptionBefore:   *beforeFlag,
+			cli.FlagOptionPath:     *pathFlag,
+			cli.FlagOptionOutput:   *outputFlag,
+		}
+		for option, value := range options {
+			if value != "" {
+				cliOpts.FlagOptions[option] = value
+			}
+		}
+	}
+	return cliOpts
+}
+
+func handleImportFlagset(args []string) cli.CLIOpts {
+	return handleImportFlags("import", "<file>", "", args)
+}
+
+func handleImportMarkdownFlagset(args []string) cli.CLIOpts {
+	return handleImportFlags("import-md", "<file or directory>", cli.InputMarkdown, args)
+}
+
+// handleImportFlags parses the flags shared by the import commands, format is left empty when -input chooses it
+func handleImportFlags(name string, usage string, format string, args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeImport
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	importCmd := flag.NewFlagSet(name, flag.ExitOnError)
+	conflictFlag := importCmd.String("on-conflict", "skip", "What to do with a code snippet whose uuid already exists with different content: skip, overwrite or new-version")
+	dryRunFlag := importCmd.Bool("dry-run", false, "Report what would be imported without changing the database")
+	inputFlag := &format
+	if format == "" {
+		inputFlag = importCmd.String("input", "", "Import format, one of "+strings.Join(cli.ImportFormats, ", ")+". Defaults to the extension of the file")
+	}
+	importCmd.Usage = func() {
+		fmt.Printf("Usage of %s: csnip %s [flags] %s\n", name, name, usage)
+		fmt.Println("  use - as the file to read from stdin")
+		if format == "" {
+			fmt.Println("  a directory imports every file in it for the -input format of another tool, such as navi")
+		} else {
+			fmt.Println("  a directory imports every file in it")
+		}
+		importCmd.PrintDefaults()
+	}
+
+	// the file may come before or after the flags
+	importCmd.Parse(args)
+	path := importCmd.Arg(0)
+	importCmd.Parse(importCmd.Args()[min(1, importCmd.NArg()):])
+	if path == "" || importCmd.NArg() > 0 {
+		importCmd.Usage()
+		os.Exit(1)
+	}
+
+	if *inputFlag != "" && !slices.Contains(cli.ImportFormats, *inputFlag) {
+		fmt.Println("Unknown import format: ", *inputFlag)
+		importCmd.Usage()
+		os.Exit(1)
+	}
+
+	cliOpts.FlagOptions[cli.FlagOptionPath] = path
+	cliOpts.FlagOptions[cli.FlagOptionConflict] = *conflictFlag
+	if *dryRunFlag {
+		cliOpts.FlagOptions[cli.FlagOptionDryRun] = "true"
+	}
+	if *inputFlag != "" {
+		cliOpts.FlagOptions[cli.FlagOptionInput] = *inputFlag
+	}
+	return cliOpts
+}
+
+func handleExportMarkdownFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeExportMarkdown
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	exportCmd := flag.NewFlagSet("export-md", flag.ExitOnError)
+	langFlag := exportCmd.String("l", "", "Only export code snippets matching any of a comma seperated list of languages")
+	tagFlag := exportCmd.String("t", "", "Only export code snippets matching any of a comma seperated list of tags")
+	allTagsFlag := exportCmd.Bool("all-tags", false, "Only match code snippets that have every tag provided with -t")
+	groupFlag := exportCmd.String("g", "", "Only export the code snippets in the group, in the group's order")
+	sourceFlag := exportCmd.String("s", "", "Only export code snippets whose source contains the text")
+	nameFlag := exportCmd.String("n", "", "Only export code snippets whose name contains the text, * and ? can be used as wildcards")
+	sinceFlag := exportCmd.String("since", "", "Only code snippets changed since a date (2006-01-02) or age (30d)")
+	beforeFlag := exportCmd.String("before", "", "Only code snippets last changed before a date (2006-01-02) or age (30d)")
+	titleFlag := exportCmd.String("title", "", "Title of the cheat sheet. Defaults to the name of the group given with -g")
+	pathFlag := exportCmd.String("o", "", "File to write the cheat sheet to. Defaults to stdout")
+
+	exportCmd.Parse(args)
+	if exportCmd.Parsed() {
+		if *allTagsFlag {
+			cliOpts.FlagOptions[cli.FlagOptionTagMatch] = "all"
+		}
+
+		// only the options that were set are added
+		options := map[cli.FlagOption]string{
+			cli.FlagOptionLanguage: *langFlag,
+			cli.FlagOptionTag:      *tagFlag,
+			cli.FlagOptionGroup:    *groupFlag,
+			cli.FlagOptionSource:   *sourceFlag,
+			cli.FlagOptionName:     *nameFlag,
+			cli.FlagOptionSince:    *sinceFlag,
+			cli.FlagOptionBefore:   *beforeFlag,
+			cli.FlagOptionTitle:    *titleFlag,
+			cli.FlagOptionPath:     *pathFlag,
+		}
+		for option, value := range options {
+			if value != "" {
+				cliOpts.FlagOptions[option] = value
+			}
+		}
+	}
+	return cliOpts
+}
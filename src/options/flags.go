@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/Ryan-Har/csnip/cli"
-	"github.com/Ryan-Har/csnip/common"
+	"github.com/Ryan-Har/csnip/config"
 )
 
 type RunType int
@@ -17,11 +19,13 @@ const (
 	RunTypeDaemon RunType = iota
 	RunTypeCli
 	RunTypeTui
+	RunTypeLsp
 )
 
 type Options struct {
 	RunType RunType
 	CliOpts cli.CLIOpts
+	Config  config.Config
 }
 
 func GetOptions() (Options, error) {
@@ -30,12 +34,16 @@ func GetOptions() (Options, error) {
 	// global flags
 	helpFlag := flag.Bool("h", false, "Show help message")
 	dFlag := flag.Bool("d", false, "Run as Daemon")
+	dbFlag := flag.String("db", "", "Path to the database, overrides the config file and "+config.EnvDatabasePath)
+	socketFlag := flag.String("socket", "", "Path of the unix socket served with -d, overrides the config file")
+	listenFlag := flag.String("listen", "", "Optional tcp address such as 127.0.0.1:7420 also served with -d, overrides the config file")
 
 	flag.Parse()
 
 	if *helpFlag {
 		fmt.Println("csnip <subcommand> flags")
-		fmt.Println("  subcommands: get, add, update")
+		fmt.Println("  run csnip without a subcommand to browse snippets interactively")
+		fmt.Println("  subcommands: get, show, add, update, edit, history, revert, diff, delete, restore, trash, search, tags, group, library, copy-to, export, import, export-md, import-md, site, config, lsp")
 		fmt.Println("  csnip <subcommand> -h for help")
 		fmt.Println()
 		flag.Usage()
@@ -43,72 +51,172 @@ func GetOptions() (Options, error) {
 		os.Exit(0)
 	}
 
+	cfg, err := config.Load()
+	if err != nil {
+		return opt, err
+	}
+	if *dbFlag != "" {
+		cfg.Database = *dbFlag
+	}
+	if *socketFlag != "" {
+		cfg.Socket = *socketFlag
+	}
+	if *listenFlag != "" {
+		cfg.Listen = *listenFlag
+	}
+	opt.Config = cfg
+
+	args := flag.Args()
 	if *dFlag {
-		opt.RunType = RunTypeDaemon // not yet implemented
+		opt.RunType = RunTypeDaemon
+		return opt, nil
+	} else if len(args) == 0 {
+		opt.RunType = RunTypeTui
 		return opt, nil
-	} else if len(os.Args) == 1 {
-		opt.RunType = RunTypeTui // not yet implemented
+	} else if strings.EqualFold(args[0], "lsp") {
+		handleLspFlagset(args[1:])
+		opt.RunType = RunTypeLsp
 		return opt, nil
 	} else {
 		opt.RunType = RunTypeCli
 	}
 
-	switch strings.ToLower(os.Args[1]) {
+	switch strings.ToLower(args[0]) {
 	case "get":
-		opt.CliOpts = handleGetFlagset()
+		opt.CliOpts = handleGetFlagset(args[1:])
 	case "add":
-		opt.CliOpts = handleAddFlagset()
+		opt.CliOpts = handleAddFlagset(args[1:])
 	case "update":
-		opt.CliOpts = handleUpdateFlagset()
+		opt.CliOpts = handleUpdateFlagset(args[1:])
+	case "edit":
+		opt.CliOpts = handleEditFlagset(args[1:])
+	case "history":
+		opt.CliOpts = handleHistoryFlagset(args[1:])
+	case "revert":
+		opt.CliOpts = handleRevertFlagset(args[1:])
+	case "diff":
+		opt.CliOpts = handleDiffFlagset(args[1:])
 	case "delete":
-		opt.CliOpts = handleDeleteFlagset()
+		opt.CliOpts = handleDeleteFlagset(args[1:])
+	case "restore":
+		opt.CliOpts = handleRestoreFlagset(args[1:])
+	case "trash":
+		opt.CliOpts = handleTrashArgs(args[1:])
+	case "group":
+		opt.CliOpts = handleGroupArgs(args[1:])
+	case "tags":
+		opt.CliOpts = cli.CLIOpts{OptType: cli.OptTypeTags, FlagOptions: map[cli.FlagOption]string{}}
+	case "search":
+		opt.CliOpts = handleSearchFlagset(args[1:])
+	case "config":
+		opt.CliOpts = handleConfigArgs(args[1:])
+	case "library":
+		opt.CliOpts = handleLibraryArgs(args[1:])
+	case "copy-to":
+		opt.CliOpts = handleCopyToArgs(args[1:])
+	case "export":
+		opt.CliOpts = handleExportFlagset(args[1:])
+	case "import":
+		opt.CliOpts = handleImportFlagset(args[1:])
+	case "import-md":
+		opt.CliOpts = handleImportMarkdownFlagset(args[1:])
+	case "export-md":
+		opt.CliOpts = handleExportMarkdownFlagset(args[1:])
+	case "show":
+		opt.CliOpts = handleShowFlagset(args[1:])
+	case "site":
+		opt.CliOpts = handleSiteFlagset(args[1:])
 	default:
-		fmt.Println("Unknown command: ", os.Args[1])
+		fmt.Println("Unknown command: ", args[0])
 		os.Exit(1)
 	}
 
-	opt.CliOpts.Theme = CodeSyntaxHighlightingTheme
+	opt.CliOpts.Config = cfg
+	opt.CliOpts.Theme = cfg.Theme
+	opt.CliOpts.Formatter = cfg.Formatter
+	opt.CliOpts.Clipboard = cfg.Clipboard
+	opt.CliOpts.PageSize = cfg.PageSize
 	return opt, nil
 }
 
-func handleGetFlagset() cli.CLIOpts {
+// handleLspFlagset only provides help, the server is configured by the client over the protocol
+func handleLspFlagset(args []string) {
+	lspCmd := flag.NewFlagSet("lsp", flag.ExitOnError)
+	lspCmd.Usage = func() {
+		fmt.Println("Usage of lsp: csnip lsp")
+		fmt.Println("  serves snippets as completions to an editor over the language server protocol on stdin and stdout")
+	}
+	lspCmd.Parse(args)
+}
+
+func handleGetFlagset(args []string) cli.CLIOpts {
 	var cliOpts cli.CLIOpts
 	cliOpts.OptType = cli.OptTypeGet
 	cliOpts.FlagOptions = map[cli.FlagOption]string{}
 
 	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
 	allFlag := getCmd.Bool("a", false, "Get a list of code snippets without filtering")
-	langFlag := getCmd.String("l", "", "Get a list of code snippets matching the language")
-	tagFlag := getCmd.String("t", "", "Get a list of code snippets matching the tag")
+	langFlag := getCmd.String("l", "", "Get a list of code snippets matching any of a comma seperated list of languages")
+	tagFlag := getCmd.String("t", "", "Get a list of code snippets matching any of a comma seperated list of tags")
+	allTagsFlag := getCmd.Bool("all-tags", false, "Only match code snippets that have every tag provided with -t")
 	idFlag := getCmd.String("i", "", "Get by uuid of the code snippet")
-
-	getCmd.Parse(os.Args[2:])
+	groupFlag := getCmd.String("g", "", "Get a list of the code snippets in the group")
+	sourceFlag := getCmd.String("s", "", "Get a list of code snippets whose source contains the text")
+	nameFlag := getCmd.String("n", "", "Get a list of code snippets whose name contains the text, * and ? can be used as wildcards")
+	sinceFlag := getCmd.String("since", "", "Only code snippets changed since a date (2006-01-02) or age (30d)")
+	beforeFlag := getCmd.String("before", "", "Only code snippets last changed before a date (2006-01-02) or age (30d)")
+	pageFlag := getCmd.Int64("page", 1, "Page of the list of code snippets to show")
+	limitFlag := getCmd.Int64("limit", 0, "Number of code snippets on each page, defaults to the configured page size")
+	sortFlag := getCmd.String("sort", "", "Sort the list of code snippets by name, date, language or usage")
+	reverseFlag := getCmd.Bool("reverse", false, "Reverse the sort order")
+	outputFlag, formatFlag := addOutputFlags(getCmd)
+
+	getCmd.Parse(args)
 	if getCmd.Parsed() {
-		if getCmd.NFlag() == 0 || *allFlag {
-			cliOpts.FlagOptions[cli.FlagOptionAll] = "all"
-		}
+		setOutputOptions(&cliOpts, getCmd, *outputFlag, *formatFlag)
 		if *idFlag != "" {
 			cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
 			return cliOpts
 		}
-		if *langFlag != "" && *tagFlag != "" {
-			cliOpts.FlagOptions[cli.FlagOptionLanguage] = *langFlag
-			cliOpts.FlagOptions[cli.FlagOptionTag] = *tagFlag
-			return cliOpts
+		if *allTagsFlag {
+			cliOpts.FlagOptions[cli.FlagOptionTagMatch] = "all"
 		}
-		if *langFlag != "" {
-			cliOpts.FlagOptions[cli.FlagOptionLanguage] = *langFlag
-			return cliOpts
+		if *reverseFlag {
+			cliOpts.FlagOptions[cli.FlagOptionReverse] = "true"
 		}
-		if *tagFlag != "" {
-			cliOpts.FlagOptions[cli.FlagOptionTag] = *tagFlag
-			return cliOpts
+		if *sortFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionSort] = *sortFlag
+		}
+		cliOpts.FlagOptions[cli.FlagOptionPage] = strconv.FormatInt(*pageFlag, 10)
+		if *limitFlag > 0 {
+			cliOpts.FlagOptions[cli.FlagOptionLimit] = strconv.FormatInt(*limitFlag, 10)
+		}
+
+		// filters can be combined, only those that were set are added
+		filters := map[cli.FlagOption]string{
+			cli.FlagOptionLanguage: *langFlag,
+			cli.FlagOptionTag:      *tagFlag,
+			cli.FlagOptionGroup:    *groupFlag,
+			cli.FlagOptionSource:   *sourceFlag,
+			cli.FlagOptionName:     *nameFlag,
+			cli.FlagOptionSince:    *sinceFlag,
+			cli.FlagOptionBefore:   *beforeFlag,
+		}
+		filtered := false
+		for option, value := range filters {
+			if value != "" {
+				cliOpts.FlagOptions[option] = value
+				filtered = true
+			}
+		}
+		if !filtered || *allFlag {
+			cliOpts.FlagOptions[cli.FlagOptionAll] = "all"
 		}
 	}
 	return cliOpts
 }
 
-func handleAddFlagset() cli.CLIOpts {
+func handleAddFlagset(args []string) cli.CLIOpts {
 	var cliOpts cli.CLIOpts
 	cliOpts.OptType = cli.OptTypeAdd
 	cliOpts.FlagOptions = map[cli.FlagOption]string{}
@@ -116,44 +224,26 @@ func handleAddFlagset() cli.CLIOpts {
 	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
 	nameFlag := addCmd.String("n", "", "Optional friendly Name used to reference the snippet of code")
 	codeFlag := addCmd.String("c", "", "The snippet of code being stored. \"-\" to read from stdin, provide a file or a string of code")
-	langFlag := addCmd.String("l", "", "Language of the snippet of code")
+	langFlag := addCmd.String("l", "", "Optional language of the snippet of code, detected from the file extension or the code when omitted")
 	tagsFlag := addCmd.String("t", "", "Optional comma seperated list of tags to assign to the snippet of code")
 	descriptionFlag := addCmd.String("d", "", "Optional description for the snippet of code")
 
-	addCmd.Parse(os.Args[2:])
+	addCmd.Parse(args)
 	if addCmd.Parsed() {
 
-		if addCmd.NFlag() == 0 || *codeFlag == "" || *langFlag == "" {
-			fmt.Println("Both code (-c) and language (-l) flags must be used")
+		if addCmd.NFlag() == 0 || *codeFlag == "" {
+			fmt.Println("The code (-c) flag must be used")
 			addCmd.Usage()
 			os.Exit(1)
 		}
 
-		// read from stdin with - or read from file if it exists, otherwise treat it as code input.
-		if *codeFlag == "-" {
-			input, err := io.ReadAll(os.Stdin)
-			if err != nil {
-				fmt.Println("Error reading from stdin")
-				os.Exit(1)
-			}
-			cliOpts.FlagOptions[cli.FlagOptionCode] = string(input)
-		} else if _, err := os.Stat(*codeFlag); err == nil {
-			content, err := os.ReadFile(*codeFlag)
-			if err != nil {
-				fmt.Println("Error reading file: ", *codeFlag)
-				os.Exit(1)
-			}
-			cliOpts.FlagOptions[cli.FlagOptionCode] = string(content)
-		} else {
-			cliOpts.FlagOptions[cli.FlagOptionCode] = *codeFlag
+		code, file := readCodeFlag(*codeFlag)
+		cliOpts.FlagOptions[cli.FlagOptionCode] = code
+		if file != "" {
+			cliOpts.FlagOptions[cli.FlagOptionCodeFile] = file
 		}
 
-		if !common.ValidateLanguage(*langFlag) {
-			// prompt for the a supported language
-			fmt.Println("Language provided unknown, please enter one of the following:")
-			fmt.Println(strings.Join(common.ListValidLanguages(), ","))
-			os.Exit(1)
-		} else {
+		if *langFlag != "" {
 			cliOpts.FlagOptions[cli.FlagOptionLanguage] = *langFlag
 		}
 
@@ -172,7 +262,7 @@ func handleAddFlagset() cli.CLIOpts {
 	return cliOpts
 }
 
-func handleUpdateFlagset() cli.CLIOpts {
+func handleUpdateFlagset(args []string) cli.CLIOpts {
 	var cliOpts cli.CLIOpts
 	cliOpts.OptType = cli.OptTypeUpdate
 	cliOpts.FlagOptions = map[cli.FlagOption]string{}
@@ -180,49 +270,96 @@ func handleUpdateFlagset() cli.CLIOpts {
 	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
 	idFlag := updateCmd.String("i", "", "Get by uuid of the code snippet")
 	codeFlag := updateCmd.String("c", "", "The snippet of code being stored. \"-\" to read from stdin, provide a file or a string of code")
+	nameFlag := updateCmd.String("n", "", "New name for the code snippet")
+	tagFlag := updateCmd.String("t", "", "Comma seperated list of tags, replacing the current tags")
+	descriptionFlag := updateCmd.String("d", "", "New description for the code snippet")
+	sourceFlag := updateCmd.String("s", "", "New source of the code snippet")
+	langFlag := updateCmd.String("l", "", "New language of the code snippet")
 
-	updateCmd.Parse(os.Args[2:])
+	updateCmd.Parse(args)
 	if updateCmd.Parsed() {
-		if updateCmd.NFlag() == 0 || *codeFlag == "" || *idFlag == "" {
-			fmt.Println("Both code (-c) and uuid (-i) flags must be used")
+		if *idFlag == "" || (*codeFlag == "" && *nameFlag == "" && *tagFlag == "" && *descriptionFlag == "" && *sourceFlag == "" && *langFlag == "") {
+			fmt.Println("The uuid (-i) flag and at least one of code (-c), name (-n), tags (-t), description (-d), source (-s) or language (-l) must be used")
 			updateCmd.Usage()
 			os.Exit(1)
 		}
 
 		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
 
-		// read from stdin with - or read from file if it exists, otherwise treat it as code input.
-		if *codeFlag == "-" {
-			input, err := io.ReadAll(os.Stdin)
-			if err != nil {
-				fmt.Println("Error reading from stdin")
-				os.Exit(1)
-			}
-			cliOpts.FlagOptions[cli.FlagOptionCode] = string(input)
-		} else if _, err := os.Stat(*codeFlag); err == nil {
-			content, err := os.ReadFile(*codeFlag)
-			if err != nil {
-				fmt.Println("Error reading file: ", *codeFlag)
-				os.Exit(1)
+		fields := map[cli.FlagOption]string{
+			cli.FlagOptionName:        *nameFlag,
+			cli.FlagOptionTag:         *tagFlag,
+			cli.FlagOptionDescription: *descriptionFlag,
+			cli.FlagOptionSource:      *sourceFlag,
+			cli.FlagOptionLanguage:    *langFlag,
+		}
+		for option, value := range fields {
+			if value != "" {
+				cliOpts.FlagOptions[option] = value
 			}
-			cliOpts.FlagOptions[cli.FlagOptionCode] = string(content)
-		} else {
-			cliOpts.FlagOptions[cli.FlagOptionCode] = *codeFlag
+		}
+
+		if *codeFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionCode], _ = readCodeFlag(*codeFlag)
 		}
 	}
 
 	return cliOpts
 }
 
-func handleDeleteFlagset() cli.CLIOpts {
+// readCodeFlag reads from stdin with - or read from file if it exists, otherwise treat it as code input.
+// The name of the file is returned when the code was read from one.
+func readCodeFlag(code string) (string, string) {
+	if code == "-" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Println("Error reading from stdin")
+			os.Exit(1)
+		}
+		return string(input), ""
+	} else if _, err := os.Stat(code); err == nil {
+		content, err := os.ReadFile(code)
+		if err != nil {
+			fmt.Println("Error reading file: ", code)
+			os.Exit(1)
+		}
+		return string(content), code
+	}
+	return code, ""
+}
+
+func handleEditFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeEdit
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	editCmd := flag.NewFlagSet("edit", flag.ExitOnError)
+	idFlag := editCmd.String("i", "", "uuid of the code snippet to open in $VISUAL or $EDITOR")
+
+	editCmd.Parse(args)
+	if editCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println(" uuid (-i) flags must be used")
+			editCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+	}
+
+	return cliOpts
+}
+
+func handleDeleteFlagset(args []string) cli.CLIOpts {
 	var cliOpts cli.CLIOpts
 	cliOpts.OptType = cli.OptTypeDelete
 	cliOpts.FlagOptions = map[cli.FlagOption]string{}
 
 	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
-	idFlag := deleteCmd.String("i", "", "Delete by uuid of the code snippet")
+	idFlag := deleteCmd.String("i", "", "Delete by uuid of the code snippet, moving it to the trash")
+	purgeFlag := deleteCmd.Bool("purge", false, "Permanently delete every version of the code snippet instead of moving it to the trash")
 
-	deleteCmd.Parse(os.Args[2:])
+	deleteCmd.Parse(args)
 	if deleteCmd.Parsed() {
 		if deleteCmd.NFlag() == 0 || *idFlag == "" {
 			fmt.Println(" uuid (-i) flags must be used")
@@ -231,7 +368,112 @@ func handleDeleteFlagset() cli.CLIOpts {
 		}
 
 		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		if *purgeFlag {
+			cliOpts.FlagOptions[cli.FlagOptionPurge] = "true"
+		}
 	}
 
 	return cliOpts
 }
+
+func handleSearchFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeSearch
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+	searchCmd.Usage = func() {
+		fmt.Println("csnip search [flags] <query>")
+		searchCmd.PrintDefaults()
+	}
+	langFlag := searchCmd.String("l", "", "Only search code snippets matching the language")
+	limitFlag := searchCmd.Int64("limit", 0, "Maximum number of results, defaults to the configured page size")
+	outputFlag, formatFlag := addOutputFlags(searchCmd)
+
+	searchCmd.Parse(args)
+	if searchCmd.Parsed() {
+		query := strings.Join(searchCmd.Args(), " ")
+		if strings.TrimSpace(query) == "" {
+			fmt.Println("A search query must be provided")
+			searchCmd.Usage()
+			os.Exit(1)
+		}
+		cliOpts.FlagOptions[cli.FlagOptionQuery] = query
+		setOutputOptions(&cliOpts, searchCmd, *outputFlag, *formatFlag)
+
+		if *langFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionLanguage] = *langFlag
+		}
+		if *limitFlag > 0 {
+			cliOpts.FlagOptions[cli.FlagOptionLimit] = strconv.FormatInt(*limitFlag, 10)
+		}
+	}
+
+	return cliOpts
+}
+
+func handleConfigArgs(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	usage := func() {
+		fmt.Println("csnip config list")
+		fmt.Println("csnip config get <key>")
+		fmt.Println("csnip config set <key> <value>")
+		fmt.Println("  keys:", strings.Join(config.Keys(), ", "))
+	}
+
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		cliOpts.OptType = cli.OptTypeConfigList
+	case "get":
+		if len(args) != 2 {
+			usage()
+			os.Exit(1)
+		}
+		cliOpts.OptType = cli.OptTypeConfigGet
+		cliOpts.FlagOptions[cli.FlagOptionConfigKey] = args[1]
+	case "set":
+		if len(args) != 3 {
+			usage()
+			os.Exit(1)
+		}
+		cliOpts.OptType = cli.OptTypeConfigSet
+		cliOpts.FlagOptions[cli.FlagOptionConfigKey] = args[1]
+		cliOpts.FlagOptions[cli.FlagOptionConfigValue] = args[2]
+	case "-h", "--help", "help":
+		usage()
+		os.Exit(0)
+	default:
+		fmt.Println("Unknown config command: ", args[0])
+		usage()
+		os.Exit(1)
+	}
+
+	return cliOpts
+}
+
+// addOutputFlags adds the flags choosing how a list of results is written
+func addOutputFlags(fs *flag.FlagSet) (output *string, format *string) {
+	output = fs.String("output", cli.OutputTable, "Output format, one of "+strings.Join(cli.OutputFormats, ", "))
+	format = fs.String("format", "", "Go template written for each result, e.g. '{{.Name}} {{.Uuid}}'")
+	return output, format
+}
+
+// setOutputOptions validates and stores the output flags
+func setOutputOptions(cliOpts *cli.CLIOpts, fs *flag.FlagSet, output string, format string) {
+	if !slices.Contains(cli.OutputFormats, output) {
+		fmt.Println("Unknown output format: ", output)
+		fs.Usage()
+		os.Exit(1)
+	}
+	cliOpts.FlagOptions[cli.FlagOptionOutput] = output
+	if format != "" {
+		cliOpts.FlagOptions[cli.FlagOptionFormat] = format
+	}
+}
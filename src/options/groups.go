@@ -0,0 +1,88 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleGroupArgs(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	usage := func() {
+		fmt.Println("csnip group <command> flags")
+		fmt.Println("  commands: create, list, show, add, remove, delete")
+		fmt.Println("  csnip group <command> -h for help")
+	}
+
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	command := strings.ToLower(args[0])
+	groupCmd := flag.NewFlagSet("group "+command, flag.ExitOnError)
+	nameFlag := groupCmd.String("n", "", "Name of the group")
+
+	var descriptionFlag, idFlag *string
+	switch command {
+	case "create":
+		cliOpts.OptType = cli.OptTypeGroupCreate
+		descriptionFlag = groupCmd.String("d", "", "Optional description for the group")
+	case "list":
+		cliOpts.OptType = cli.OptTypeGroupList
+		idFlag = groupCmd.String("i", "", "Optional uuid of a code snippet, only list the groups it is in")
+	case "show":
+		cliOpts.OptType = cli.OptTypeGroupShow
+	case "add":
+		cliOpts.OptType = cli.OptTypeGroupAdd
+		idFlag = groupCmd.String("i", "", "uuid of the code snippet to add to the group")
+	case "remove":
+		cliOpts.OptType = cli.OptTypeGroupRemove
+		idFlag = groupCmd.String("i", "", "uuid of the code snippet to remove from the group")
+	case "delete":
+		cliOpts.OptType = cli.OptTypeGroupDelete
+	case "-h", "--help", "help":
+		usage()
+		os.Exit(0)
+	default:
+		fmt.Println("Unknown group command: ", args[0])
+		usage()
+		os.Exit(1)
+	}
+
+	groupCmd.Parse(args[1:])
+	if groupCmd.Parsed() {
+		if cliOpts.OptType == cli.OptTypeGroupList {
+			if *idFlag != "" {
+				cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+			}
+			return cliOpts
+		}
+
+		if *nameFlag == "" {
+			fmt.Println("Group name (-n) flag must be used")
+			groupCmd.Usage()
+			os.Exit(1)
+		}
+		cliOpts.FlagOptions[cli.FlagOptionGroup] = *nameFlag
+
+		if idFlag != nil {
+			if *idFlag == "" {
+				fmt.Println("Both group name (-n) and uuid (-i) flags must be used")
+				groupCmd.Usage()
+				os.Exit(1)
+			}
+			cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		}
+		if descriptionFlag != nil && *descriptionFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionDescription] = *descriptionFlag
+		}
+	}
+
+	return cliOpts
+}
@@ -0,0 +1,83 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleHistoryFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeHistory
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	idFlag := historyCmd.String("i", "", "uuid of the code snippet")
+	outputFlag, formatFlag := addOutputFlags(historyCmd)
+
+	historyCmd.Parse(args)
+	if historyCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println(" uuid (-i) flags must be used")
+			historyCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		setOutputOptions(&cliOpts, historyCmd, *outputFlag, *formatFlag)
+	}
+
+	return cliOpts
+}
+
+func handleRevertFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeRevert
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	revertCmd := flag.NewFlagSet("revert", flag.ExitOnError)
+	idFlag := revertCmd.String("i", "", "uuid of the code snippet")
+	toFlag := revertCmd.String("to", "", "Version to revert to, it is copied into a new latest version")
+
+	revertCmd.Parse(args)
+	if revertCmd.Parsed() {
+		if *idFlag == "" || *toFlag == "" {
+			fmt.Println("Both uuid (-i) and version (-to) flags must be used")
+			revertCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		cliOpts.FlagOptions[cli.FlagOptionVersion] = *toFlag
+	}
+
+	return cliOpts
+}
+
+func handleDiffFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeDiff
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+	idFlag := diffCmd.String("i", "", "uuid of the code snippet")
+	fromFlag := diffCmd.String("from", "", "Version to compare from, defaults to the version before -to")
+	toFlag := diffCmd.String("to", "", "Version to compare to, defaults to the latest version")
+
+	diffCmd.Parse(args)
+	if diffCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println(" uuid (-i) flags must be used")
+			diffCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		cliOpts.FlagOptions[cli.FlagOptionFromVersion] = *fromFlag
+		cliOpts.FlagOptions[cli.FlagOptionVersion] = *toFlag
+	}
+
+	return cliOpts
+}
@@ -0,0 +1,102 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleLibraryArgs(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	usage := func() {
+		fmt.Println("csnip library <command> flags")
+		fmt.Println("  commands: list, add, remove")
+		fmt.Println("  csnip library <command> -h for help")
+	}
+
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	command := strings.ToLower(args[0])
+	libraryCmd := flag.NewFlagSet("library "+command, flag.ExitOnError)
+
+	var nameFlag, pathFlag *string
+	switch command {
+	case "list":
+		cliOpts.OptType = cli.OptTypeLibraryList
+	case "add":
+		cliOpts.OptType = cli.OptTypeLibraryAdd
+		nameFlag = libraryCmd.String("n", "", "Name of the library")
+		pathFlag = libraryCmd.String("p", "", "Path to the library's database, it is created if it does not exist")
+	case "remove":
+		cliOpts.OptType = cli.OptTypeLibraryRemove
+		nameFlag = libraryCmd.String("n", "", "Name of the library, its database is not deleted")
+	case "-h", "--help", "help":
+		usage()
+		os.Exit(0)
+	default:
+		fmt.Println("Unknown library command: ", args[0])
+		usage()
+		os.Exit(1)
+	}
+
+	libraryCmd.Parse(args[1:])
+	if libraryCmd.Parsed() {
+		if nameFlag != nil {
+			if *nameFlag == "" {
+				fmt.Println("Library name (-n) flag must be used")
+				libraryCmd.Usage()
+				os.Exit(1)
+			}
+			cliOpts.FlagOptions[cli.FlagOptionLibrary] = *nameFlag
+		}
+		if pathFlag != nil {
+			if *pathFlag == "" {
+				fmt.Println("Both library name (-n) and path (-p) flags must be used")
+				libraryCmd.Usage()
+				os.Exit(1)
+			}
+			cliOpts.FlagOptions[cli.FlagOptionPath] = *pathFlag
+		}
+	}
+
+	return cliOpts
+}
+
+func handleCopyToArgs(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeCopyTo
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	copyCmd := flag.NewFlagSet("copy-to", flag.ExitOnError)
+	idFlag := copyCmd.String("i", "", "uuid of the code snippet to copy")
+	copyCmd.Usage = func() {
+		fmt.Println("Usage of copy-to: csnip copy-to <library> -i <uuid>")
+		copyCmd.PrintDefaults()
+	}
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		copyCmd.Usage()
+		os.Exit(1)
+	}
+	cliOpts.FlagOptions[cli.FlagOptionLibrary] = args[0]
+
+	copyCmd.Parse(args[1:])
+	if copyCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println("The uuid (-i) flag must be used")
+			copyCmd.Usage()
+			os.Exit(1)
+		}
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+	}
+
+	return cliOpts
+}
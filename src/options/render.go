@@ -0,0 +1,68 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleShowFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeShow
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	showCmd := flag.NewFlagSet("show", flag.ExitOnError)
+	idFlag := showCmd.String("i", "", "uuid of the code snippet")
+	formatFlag := showCmd.String("format", cli.RenderANSI, "Format to render the code snippet in, one of "+strings.Join(cli.RenderFormats, ", "))
+	pathFlag := showCmd.String("o", "", "File to write the rendered code snippet to. Defaults to stdout")
+
+	showCmd.Parse(args)
+	if showCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println(" uuid (-i) flags must be used")
+			showCmd.Usage()
+			os.Exit(1)
+		}
+		if !slices.Contains(cli.RenderFormats, *formatFlag) {
+			fmt.Println("Unknown format: ", *formatFlag)
+			showCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+		cliOpts.FlagOptions[cli.FlagOptionRender] = *formatFlag
+		if *pathFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionPath] = *pathFlag
+		}
+	}
+	return cliOpts
+}
+
+func handleSiteFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeSite
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	siteCmd := flag.NewFlagSet("site", flag.ExitOnError)
+	pathFlag := siteCmd.String("o", "public", "Directory to write the site to")
+	titleFlag := siteCmd.String("title", "", "Title of the site. Defaults to Snippets")
+
+	siteCmd.Parse(args)
+	if siteCmd.Parsed() {
+		if *pathFlag == "" {
+			fmt.Println(" a directory (-o) must be given")
+			siteCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionPath] = *pathFlag
+		if *titleFlag != "" {
+			cliOpts.FlagOptions[cli.FlagOptionTitle] = *titleFlag
+		}
+	}
+	return cliOpts
+}
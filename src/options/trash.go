@@ -0,0 +1,74 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+)
+
+func handleTrashArgs(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	usage := func() {
+		fmt.Println("csnip trash <command> flags")
+		fmt.Println("  commands: list, empty")
+		fmt.Println("  csnip trash <command> -h for help")
+	}
+
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	command := strings.ToLower(args[0])
+	trashCmd := flag.NewFlagSet("trash "+command, flag.ExitOnError)
+
+	var olderThanFlag *string
+	switch command {
+	case "list":
+		cliOpts.OptType = cli.OptTypeTrashList
+	case "empty":
+		cliOpts.OptType = cli.OptTypeTrashEmpty
+		olderThanFlag = trashCmd.String("older-than", "", "Only permanently delete snippets that have been in the trash this long, e.g. 30d, 12h")
+	case "-h", "--help", "help":
+		usage()
+		os.Exit(0)
+	default:
+		fmt.Println("Unknown trash command: ", args[0])
+		usage()
+		os.Exit(1)
+	}
+
+	trashCmd.Parse(args[1:])
+	if trashCmd.Parsed() && olderThanFlag != nil && *olderThanFlag != "" {
+		cliOpts.FlagOptions[cli.FlagOptionOlderThan] = *olderThanFlag
+	}
+
+	return cliOpts
+}
+
+func handleRestoreFlagset(args []string) cli.CLIOpts {
+	var cliOpts cli.CLIOpts
+	cliOpts.OptType = cli.OptTypeRestore
+	cliOpts.FlagOptions = map[cli.FlagOption]string{}
+
+	restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+	idFlag := restoreCmd.String("i", "", "Restore by uuid of the code snippet in the trash")
+
+	restoreCmd.Parse(args)
+	if restoreCmd.Parsed() {
+		if *idFlag == "" {
+			fmt.Println(" uuid (-i) flags must be used")
+			restoreCmd.Usage()
+			os.Exit(1)
+		}
+
+		cliOpts.FlagOptions[cli.FlagOptionUUID] = *idFlag
+	}
+
+	return cliOpts
+}
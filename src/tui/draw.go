@@ -0,0 +1,273 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// minimum terminal size the UI is drawn in, anything smaller shows a message instead
+	minWidth  = 20
+	minHeight = 4
+	// below this width the list and preview are shown one at a time
+	splitWidth = 70
+)
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleBar      = tcell.StyleDefault.Reverse(true)
+	styleSelected = tcell.StyleDefault.Reverse(true).Bold(true)
+	styleDim      = tcell.StyleDefault.Dim(true)
+)
+
+func (a *app) draw() {
+	a.screen.Clear()
+	width, height := a.screen.Size()
+
+	if width < minWidth || height < minHeight {
+		drawText(a.screen, 0, 0, width, styleDefault, "Terminal too small")
+		a.screen.Show()
+		return
+	}
+
+	a.drawHeader(width)
+	a.drawStatus(width, height-1)
+
+	bodyTop, bodyHeight := 1, height-2
+	if !a.splitPanes() {
+		if a.view == viewSnippets && a.showPreview {
+			a.drawPreview(0, bodyTop, width, bodyHeight)
+		} else {
+			a.drawList(0, bodyTop, width, bodyHeight)
+		}
+		a.screen.Show()
+		return
+	}
+
+	listWidth := max(24, min(width/3, 50))
+	a.drawList(0, bodyTop, listWidth, bodyHeight)
+	for y := bodyTop; y < bodyTop+bodyHeight; y++ {
+		a.screen.SetContent(listWidth, y, tcell.RuneVLine, nil, styleDim)
+	}
+	a.drawPreview(listWidth+1, bodyTop, width-listWidth-1, bodyHeight)
+	a.screen.Show()
+}
+
+// splitPanes reports whether the terminal is wide enough for the list and preview side by side
+func (a *app) splitPanes() bool {
+	width, _ := a.screen.Size()
+	return width >= splitWidth
+}
+
+// pageSize is the number of rows moved by page up and page down
+func (a *app) pageSize() int {
+	_, height := a.screen.Size()
+	return max(1, height-3)
+}
+
+func (a *app) drawHeader(width int) {
+	fillRow(a.screen, 0, 0, width, styleBar)
+
+	parts := []string{"csnip"}
+	if a.language != "" {
+		parts = append(parts, "language: "+a.language)
+	}
+	if a.tag != "" {
+		parts = append(parts, "tag: "+a.tag)
+	}
+	if a.filtering || a.filter != "" {
+		filter := "/" + a.filter
+		if a.filtering {
+			filter += "_"
+		}
+		parts = append(parts, filter)
+	}
+	left := " " + strings.Join(parts, "  ")
+	right := fmt.Sprintf("%d/%d ", len(a.visible), len(a.snippets))
+
+	drawText(a.screen, 0, 0, width, styleBar, left)
+	if runewidth.StringWidth(left)+runewidth.StringWidth(right)+1 < width {
+		drawText(a.screen, width-runewidth.StringWidth(right), 0, width, styleBar, right)
+	}
+}
+
+func (a *app) drawStatus(width int, y int) {
+	text := a.status
+	switch {
+	case text != "":
+	case a.filtering:
+		text = "type to filter  enter keep  esc clear"
+	case a.view != viewSnippets:
+		text = "enter select  tab switch list  esc back  q quit"
+	case !a.splitPanes() && a.showPreview:
+		text = "j/k scroll  c copy  e edit  d delete  esc back  q quit"
+	default:
+		text = "/ filter  l languages  t tags  x clear  c copy  e edit  d delete  J/K scroll  q quit"
+	}
+	drawText(a.screen, 0, y, width, styleDim, text)
+}
+
+func (a *app) drawList(x, y, width, height int) {
+	switch a.view {
+	case viewLanguages:
+		a.drawFacets(x, y, width, height, "All languages", a.languages)
+		return
+	case viewTags:
+		a.drawFacets(x, y, width, height, "All tags", a.tags)
+		return
+	}
+
+	if len(a.visible) == 0 {
+		drawText(a.screen, x+1, y, width-1, styleDim, "No snippets found")
+		return
+	}
+
+	a.offset = scrollOffset(a.cursor, a.offset, height)
+	for row := 0; row < height && a.offset+row < len(a.visible); row++ {
+		i := a.offset + row
+		s := a.visible[i]
+
+		style := styleDefault
+		if i == a.cursor {
+			style = styleSelected
+			fillRow(a.screen, x, y+row, width, style)
+		}
+
+		// the language is shown on the right when there is room for it
+		lang := s.Language
+		nameWidth := width - 2
+		if runewidth.StringWidth(lang)+12 < width {
+			nameWidth -= runewidth.StringWidth(lang) + 1
+			langStyle := style
+			if i != a.cursor {
+				langStyle = styleDim
+			}
+			drawText(a.screen, x+width-runewidth.StringWidth(lang)-1, y+row, width, langStyle, lang)
+		}
+		drawText(a.screen, x+1, y+row, nameWidth, style, displayName(s))
+	}
+}
+
+func (a *app) drawFacets(x, y, width, height int, all string, list []facet) {
+	a.facetOffset = scrollOffset(a.facetCursor, a.facetOffset, height)
+	for row := 0; row < height && a.facetOffset+row <= len(list); row++ {
+		i := a.facetOffset + row
+
+		label, count := all, len(a.snippets)
+		if i > 0 {
+			label, count = list[i-1].name, list[i-1].count
+		}
+
+		style := styleDefault
+		if i == a.facetCursor {
+			style = styleSelected
+			fillRow(a.screen, x, y+row, width, style)
+		}
+		countText := fmt.Sprintf("%d", count)
+		drawText(a.screen, x+1, y+row, width-len(countText)-3, style, label)
+		drawText(a.screen, x+width-len(countText)-1, y+row, width, style, countText)
+	}
+}
+
+func (a *app) drawPreview(x, y, width, height int) {
+	s, ok := a.selected()
+	if !ok || width < 1 {
+		return
+	}
+
+	// the theme's background colours the whole pane so the code reads as it would in an editor
+	base := a.baseStyle()
+	for row := 0; row < height; row++ {
+		fillRow(a.screen, x, y+row, width, base)
+	}
+
+	header := previewHeader(s)
+	row := 0
+	for _, line := range header {
+		if row >= height {
+			return
+		}
+		style := base
+		if row == 0 {
+			style = base.Bold(true)
+		} else {
+			style = base.Dim(true)
+		}
+		drawText(a.screen, x+1, y+row, width-2, style, line)
+		row++
+	}
+	row++
+
+	lines := a.highlight(s)
+	a.previewOffset = min(a.previewOffset, max(0, len(lines)-(height-row)))
+	for i := a.previewOffset; i < len(lines) && row < height; i++ {
+		col := x + 1
+		for _, c := range lines[i] {
+			w := runewidth.RuneWidth(c.r)
+			if col+w > x+width {
+				break
+			}
+			a.screen.SetContent(col, y+row, c.r, nil, c.style)
+			col += w
+		}
+		row++
+	}
+}
+
+// previewHeader is the snippet's details shown above its code
+func previewHeader(s models.CodeSnippet) []string {
+	header := []string{displayName(s)}
+
+	details := []string{s.Language, fmt.Sprintf("v%d", s.Version)}
+	if s.Library != "" {
+		details = append(details, s.Library)
+	}
+	if len(s.Tags) > 0 {
+		details = append(details, strings.Join(s.Tags, ", "))
+	}
+	header = append(header, strings.Join(details, "  "))
+
+	if s.Description != "" {
+		header = append(header, s.Description)
+	}
+	if s.Source != "" {
+		header = append(header, s.Source)
+	}
+	return header
+}
+
+// scrollOffset returns the first row to draw so that cursor stays within a window of height rows
+func scrollOffset(cursor, offset, height int) int {
+	if cursor < offset {
+		return cursor
+	}
+	if cursor >= offset+height {
+		return cursor - height + 1
+	}
+	return offset
+}
+
+func fillRow(screen tcell.Screen, x, y, width int, style tcell.Style) {
+	for i := 0; i < width; i++ {
+		screen.SetContent(x+i, y, ' ', nil, style)
+	}
+}
+
+// drawText writes text from x, cutting it off with an ellipsis if it is wider than width
+func drawText(screen tcell.Screen, x, y, width int, style tcell.Style, text string) {
+	if width <= 0 {
+		return
+	}
+	text = strings.ReplaceAll(text, "\n", " ")
+	if runewidth.StringWidth(text) > width {
+		text = runewidth.Truncate(text, width, "…")
+	}
+	for _, r := range text {
+		screen.SetContent(x, y, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+}
@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/gdamore/tcell/v2"
+)
+
+const tabWidth = 4
+
+// cell is a single highlighted character of the preview
+type cell struct {
+	r     rune
+	style tcell.Style
+}
+
+// preview caches the highlighted lines of the snippet being previewed
+type preview struct {
+	key   string
+	lines [][]cell
+}
+
+// baseStyle is the theme's default text and background colour
+func (a *app) baseStyle() tcell.Style {
+	return tokenStyle(a.theme.Get(chroma.Background), tcell.StyleDefault)
+}
+
+// highlight splits the snippet's code into lines of cells coloured with the configured theme
+func (a *app) highlight(s models.CodeSnippet) [][]cell {
+	key := fmt.Sprintf("%s@%d", s.Uuid, s.Version)
+	if a.preview.key == key {
+		return a.preview.lines
+	}
+
+	lexer := lexers.Get(s.Language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	base := a.baseStyle()
+	lines := [][]cell{{}}
+	add := func(text string, style tcell.Style) {
+		for _, r := range text {
+			switch r {
+			case '\n':
+				lines = append(lines, []cell{})
+				continue
+			case '\r':
+				continue
+			case '\t':
+				for i := 0; i < tabWidth; i++ {
+					lines[len(lines)-1] = append(lines[len(lines)-1], cell{r: ' ', style: style})
+				}
+				continue
+			}
+			lines[len(lines)-1] = append(lines[len(lines)-1], cell{r: r, style: style})
+		}
+	}
+
+	iterator, err := lexer.Tokenise(nil, s.Code)
+	if err != nil {
+		add(s.Code, base)
+	} else {
+		for token := iterator(); token != chroma.EOF; token = iterator() {
+			add(token.Value, tokenStyle(a.theme.Get(token.Type), base))
+		}
+	}
+
+	// drop the empty line left by a trailing newline
+	if len(lines) > 1 && len(lines[len(lines)-1]) == 0 && strings.HasSuffix(s.Code, "\n") {
+		lines = lines[:len(lines)-1]
+	}
+
+	a.preview = preview{key: key, lines: lines}
+	return lines
+}
+
+// tokenStyle converts a chroma style entry to a tcell style, unset attributes are taken from base.
+// tcell reduces the colours to what the terminal supports.
+func tokenStyle(entry chroma.StyleEntry, base tcell.Style) tcell.Style {
+	style := base
+	if entry.Colour.IsSet() {
+		style = style.Foreground(rgb(entry.Colour))
+	}
+	if entry.Background.IsSet() {
+		style = style.Background(rgb(entry.Background))
+	}
+	if entry.Bold == chroma.Yes {
+		style = style.Bold(true)
+	}
+	if entry.Italic == chroma.Yes {
+		style = style.Italic(true)
+	}
+	if entry.Underline == chroma.Yes {
+		style = style.Underline(true)
+	}
+	return style
+}
+
+func rgb(c chroma.Colour) tcell.Color {
+	return tcell.NewRGBColor(int32(c.Red()), int32(c.Green()), int32(c.Blue()))
+}
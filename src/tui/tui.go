@@ -0,0 +1,474 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Ryan-Har/csnip/cli"
+	"github.com/Ryan-Har/csnip/common/models"
+	"github.com/Ryan-Har/csnip/config"
+	"github.com/Ryan-Har/csnip/database"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/atotto/clipboard"
+	"github.com/gdamore/tcell/v2"
+)
+
+// view is what the left hand pane is listing
+type view int
+
+const (
+	viewSnippets view = iota
+	viewLanguages
+	viewTags
+)
+
+// facet is a language or tag along with the number of snippets that have it
+type facet struct {
+	name  string
+	count int
+}
+
+// app holds the state of the terminal UI between events
+type app struct {
+	ctx    context.Context
+	db     database.DatabaseInteractions
+	screen tcell.Screen
+	theme  *chroma.Style
+
+	snippets  []models.CodeSnippet
+	visible   []models.CodeSnippet
+	languages []facet
+	tags      []facet
+
+	view      view
+	filter    string
+	filtering bool
+	language  string
+	tag       string
+
+	cursor        int
+	offset        int
+	facetCursor   int
+	facetOffset   int
+	previewOffset int
+
+	// on narrow terminals only one pane fits, showPreview swaps the list for the preview
+	showPreview   bool
+	confirmDelete bool
+	status        string
+
+	preview preview
+}
+
+// Run opens the full screen snippet browser and returns once it is closed
+func Run(ctx context.Context, db database.DatabaseInteractions, cfg config.Config) error {
+	theme := styles.Get(cfg.Theme)
+	if theme == nil {
+		theme = styles.Fallback
+	}
+	a := &app{ctx: ctx, db: db, theme: theme}
+
+	// load before taking over the terminal so errors are printed normally
+	if err := a.reload(); err != nil {
+		return err
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("unable to open terminal: %w", err)
+	}
+	if err := screen.Init(); err != nil {
+		return fmt.Errorf("unable to open terminal: %w", err)
+	}
+	defer screen.Fini()
+	screen.SetTitle("csnip")
+	a.screen = screen
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			screen.PostEvent(tcell.NewEventInterrupt(nil))
+		case <-done:
+		}
+	}()
+
+	return a.loop()
+}
+
+func (a *app) loop() error {
+	for {
+		a.draw()
+
+		switch ev := a.screen.PollEvent().(type) {
+		case nil, *tcell.EventInterrupt:
+			return nil
+		case *tcell.EventResize:
+			a.screen.Sync()
+		case *tcell.EventKey:
+			if quit := a.handleKey(ev); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// reload fetches every snippet again and reapplies the current filter and facets
+func (a *app) reload() error {
+	snippets, err := a.db.QuerySnippets(a.ctx, database.SnippetFilter{})
+	if err != nil && !errors.Is(err, database.ErrNoSnippetsFound) {
+		return fmt.Errorf("unable to retrieve snippets: %w", err)
+	}
+	a.snippets = snippets
+	a.languages, a.tags = facets(snippets)
+	a.applyFilter()
+	return nil
+}
+
+// facets counts the snippets for every language and tag, most used first
+func facets(snippets []models.CodeSnippet) (languages []facet, tags []facet) {
+	languageCounts := map[string]int{}
+	tagCounts := map[string]int{}
+	for _, s := range snippets {
+		languageCounts[s.Language]++
+		for _, tag := range s.Tags {
+			tagCounts[tag]++
+		}
+	}
+	return sortedFacets(languageCounts), sortedFacets(tagCounts)
+}
+
+func sortedFacets(counts map[string]int) []facet {
+	list := make([]facet, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, facet{name: name, count: count})
+	}
+	slices.SortFunc(list, func(a, b facet) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	return list
+}
+
+// applyFilter rebuilds the visible snippets, keeping the selected snippet selected if it is still shown
+func (a *app) applyFilter() {
+	var selected models.CodeSnippet
+	if s, ok := a.selected(); ok {
+		selected = s
+	}
+
+	words := strings.Fields(strings.ToLower(a.filter))
+	a.visible = a.visible[:0]
+	for _, s := range a.snippets {
+		if a.language != "" && s.Language != a.language {
+			continue
+		}
+		if a.tag != "" && !slices.Contains(s.Tags, a.tag) {
+			continue
+		}
+		if !matchesWords(s, words) {
+			continue
+		}
+		a.visible = append(a.visible, s)
+	}
+
+	a.cursor = 0
+	for i, s := range a.visible {
+		if s.Uuid == selected.Uuid {
+			a.cursor = i
+			break
+		}
+	}
+	a.previewOffset = 0
+}
+
+// matchesWords reports whether every word appears somewhere in the snippet's name, description, language, tags or source
+func matchesWords(s models.CodeSnippet, words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join([]string{s.Name, s.Description, s.Language, strings.Join(s.Tags, " "), s.Source}, " "))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *app) selected() (models.CodeSnippet, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.visible) {
+		return models.CodeSnippet{}, false
+	}
+	return a.visible[a.cursor], true
+}
+
+// handleKey acts on a key press, it returns true when the UI should close
+func (a *app) handleKey(ev *tcell.EventKey) bool {
+	a.status = ""
+
+	if ev.Key() == tcell.KeyCtrlC {
+		return true
+	}
+	if ev.Key() == tcell.KeyCtrlL {
+		a.screen.Sync()
+		return false
+	}
+
+	if a.confirmDelete {
+		a.confirmDelete = false
+		if ev.Key() == tcell.KeyRune && (ev.Rune() == 'y' || ev.Rune() == 'Y') {
+			a.deleteSelected()
+		} else {
+			a.status = "Delete cancelled"
+		}
+		return false
+	}
+
+	if a.filtering {
+		a.handleFilterKey(ev)
+		return false
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		a.move(-1)
+	case tcell.KeyDown:
+		a.move(1)
+	case tcell.KeyPgUp, tcell.KeyCtrlU:
+		a.move(-a.pageSize())
+	case tcell.KeyPgDn, tcell.KeyCtrlD:
+		a.move(a.pageSize())
+	case tcell.KeyHome:
+		a.move(-a.listLen())
+	case tcell.KeyEnd:
+		a.move(a.listLen())
+	case tcell.KeyTab:
+		a.switchView((a.view + 1) % 3)
+	case tcell.KeyBacktab:
+		a.switchView((a.view + 2) % 3)
+	case tcell.KeyEnter:
+		a.enter()
+	case tcell.KeyEscape:
+		a.back()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case 'k':
+			a.move(-1)
+		case 'j':
+			a.move(1)
+		case 'g':
+			a.move(-a.listLen())
+		case 'G':
+			a.move(a.listLen())
+		case 'K':
+			a.scrollPreview(-1)
+		case 'J':
+			a.scrollPreview(1)
+		case '/':
+			a.filtering = true
+		case 'l':
+			a.switchView(viewLanguages)
+		case 't':
+			a.switchView(viewTags)
+		case 'x':
+			a.filter, a.language, a.tag = "", "", ""
+			a.applyFilter()
+		case 'c', 'y':
+			a.copySelected()
+		case 'e':
+			a.editSelected()
+		case 'd':
+			if s, ok := a.selected(); ok && a.view == viewSnippets {
+				a.confirmDelete = true
+				a.status = fmt.Sprintf("Move %s to the trash? y/n", displayName(s))
+			}
+		case 'r':
+			if err := a.reload(); err != nil {
+				a.status = err.Error()
+			}
+		}
+	}
+	return false
+}
+
+func (a *app) handleFilterKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		a.filtering = false
+		return
+	case tcell.KeyEscape:
+		a.filtering = false
+		a.filter = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if r := []rune(a.filter); len(r) > 0 {
+			a.filter = string(r[:len(r)-1])
+		}
+	case tcell.KeyCtrlW:
+		a.filter = ""
+	case tcell.KeyRune:
+		a.filter += string(ev.Rune())
+	default:
+		return
+	}
+	a.applyFilter()
+}
+
+// listLen is the number of rows in the left hand pane
+func (a *app) listLen() int {
+	switch a.view {
+	case viewLanguages:
+		return len(a.languages) + 1
+	case viewTags:
+		return len(a.tags) + 1
+	}
+	return len(a.visible)
+}
+
+func (a *app) move(delta int) {
+	if a.view == viewSnippets && a.showPreview {
+		a.scrollPreview(delta)
+		return
+	}
+
+	cursor := &a.cursor
+	if a.view != viewSnippets {
+		cursor = &a.facetCursor
+	}
+	*cursor = max(0, min(*cursor+delta, a.listLen()-1))
+	if a.view == viewSnippets {
+		a.previewOffset = 0
+	}
+}
+
+func (a *app) scrollPreview(delta int) {
+	a.previewOffset = max(0, a.previewOffset+delta)
+}
+
+func (a *app) switchView(v view) {
+	a.view = v
+	a.showPreview = false
+	a.facetCursor, a.facetOffset = 0, 0
+
+	// start on the facet that is currently applied
+	current, list := a.language, a.languages
+	if v == viewTags {
+		current, list = a.tag, a.tags
+	}
+	for i, f := range list {
+		if f.name == current {
+			a.facetCursor = i + 1
+		}
+	}
+}
+
+// enter applies the selected facet, or shows the preview on terminals too narrow for both panes
+func (a *app) enter() {
+	switch a.view {
+	case viewSnippets:
+		if !a.splitPanes() {
+			a.showPreview = !a.showPreview
+		}
+		return
+	case viewLanguages:
+		a.language = ""
+		if a.facetCursor > 0 {
+			a.language = a.languages[a.facetCursor-1].name
+		}
+	case viewTags:
+		a.tag = ""
+		if a.facetCursor > 0 {
+			a.tag = a.tags[a.facetCursor-1].name
+		}
+	}
+	a.view = viewSnippets
+	a.applyFilter()
+}
+
+func (a *app) back() {
+	switch {
+	case a.view != viewSnippets:
+		a.view = viewSnippets
+	case a.showPreview:
+		a.showPreview = false
+	case a.filter != "":
+		a.filter = ""
+		a.applyFilter()
+	}
+}
+
+// copySelected copies the code to the system clipboard, falling back to the terminal's clipboard
+// which also works over ssh
+func (a *app) copySelected() {
+	s, ok := a.selected()
+	if !ok || a.view != viewSnippets {
+		return
+	}
+	if err := clipboard.WriteAll(s.Code); err != nil {
+		a.screen.SetClipboard([]byte(s.Code))
+		a.status = fmt.Sprintf("Copied %s using the terminal clipboard", displayName(s))
+	} else {
+		a.status = fmt.Sprintf("Copied %s to the clipboard", displayName(s))
+	}
+	_ = a.db.RecordSnippetUse(a.ctx, s.Uuid)
+}
+
+// editSelected hands the terminal to the user's editor and reloads once it is closed
+func (a *app) editSelected() {
+	s, ok := a.selected()
+	if !ok || a.view != viewSnippets {
+		return
+	}
+	if err := a.screen.Suspend(); err != nil {
+		a.status = fmt.Sprintf("unable to open editor: %v", err)
+		return
+	}
+	editErr := cli.EditSnippet(a.ctx, a.db, s.Uuid)
+	if err := a.screen.Resume(); err != nil {
+		a.status = fmt.Sprintf("unable to restore terminal: %v", err)
+		return
+	}
+
+	if err := a.reload(); err != nil {
+		a.status = err.Error()
+		return
+	}
+	if editErr != nil {
+		a.status = editErr.Error()
+		return
+	}
+	a.status = fmt.Sprintf("Edited %s", displayName(s))
+}
+
+func (a *app) deleteSelected() {
+	s, ok := a.selected()
+	if !ok {
+		return
+	}
+	if err := a.db.DeleteSnippetByUUID(a.ctx, s.Uuid); err != nil {
+		a.status = fmt.Sprintf("unable to delete snippet: %v", err)
+		return
+	}
+	if err := a.reload(); err != nil {
+		a.status = err.Error()
+		return
+	}
+	a.status = fmt.Sprintf("Moved %s to the trash, csnip restore -i %s to undo", displayName(s), s.Uuid)
+}
+
+// displayName is how a snippet is labelled in the list, snippets without a name use their uuid
+func displayName(s models.CodeSnippet) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Uuid.String()
+}